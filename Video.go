@@ -1,7 +1,10 @@
 package torrentRenamer
 
 import (
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 	"torrentRenamer/config"
 	"torrentRenamer/util"
 
@@ -12,15 +15,72 @@ type Video interface {
 	IsMovie() bool
 	IsShow() bool
 	IsValid() bool
+	GetName() string
 	SetExt(string)
+	GetExt() string
 	GetNewName() string
 	GetNewPath() string
+	GetMetadata() *Metadata
+	GetRelease() *Release
+}
+
+// Release - What the file name tells about the release, which is kept when a
+// service looks the video up so copies of the same video can be told apart
+type Release struct {
+	Resolution string `json:"resolution,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Codec      string `json:"codec,omitempty"`
+	Audio      string `json:"audio,omitempty"`
+	Proper     bool   `json:"proper,omitempty"`
+	Repack     bool   `json:"repack,omitempty"`
+}
+
+// String - Returns the release as it would appear in a file name, e.g.
+// "1080p BluRay x264 PROPER"
+func (r Release) String() string {
+	parts := make([]string, 0, 5)
+
+	for _, part := range []string{r.Resolution, r.Source, r.Codec} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	if r.Proper {
+		parts = append(parts, "PROPER")
+	}
+
+	if r.Repack {
+		parts = append(parts, "REPACK")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Metadata - Details filled in by services. They are empty for videos that were
+// only parsed from their file name.
+type Metadata struct {
+	OriginalName  string   `json:"originalName,omitempty"`
+	LocalizedName string   `json:"localizedName,omitempty"`
+	ImdbID        string   `json:"imdbId,omitempty"`
+	Genres        []string `json:"genres,omitempty"`
+	Rated         string   `json:"rated,omitempty"`
+	Runtime       int      `json:"runtime,omitempty"`
+	Director      string   `json:"director,omitempty"`
+	Plot          string   `json:"plot,omitempty"`
+	Poster        string   `json:"poster,omitempty"`
+	Fanart        string   `json:"fanart,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	Country       string   `json:"country,omitempty"`
+	TmdbID        string   `json:"tmdbId,omitempty"`
 }
 
 type Movie struct {
-	Name string `json:"name"`
-	Year int    `json:"year"`
-	Ext  string `json:"ext"`
+	Name    string  `json:"name"`
+	Year    int     `json:"year"`
+	Ext     string  `json:"ext"`
+	Release Release `json:"release"`
+	Metadata
 }
 
 func (m *Movie) IsMovie() bool {
@@ -35,10 +95,26 @@ func (m *Movie) IsValid() bool {
 	return m.Name != ""
 }
 
+func (m *Movie) GetName() string {
+	return m.Name
+}
+
 func (m *Movie) SetExt(ext string) {
 	m.Ext = ext
 }
 
+func (m *Movie) GetExt() string {
+	return m.Ext
+}
+
+func (m *Movie) GetMetadata() *Metadata {
+	return &m.Metadata
+}
+
+func (m *Movie) GetRelease() *Release {
+	return &m.Release
+}
+
 func (m *Movie) GetNewName() string {
 	config := config.GetConfig()
 
@@ -61,12 +137,22 @@ func (m *Movie) GetNewPath() string {
 	return util.JoinPaths(path, m.GetNewName())
 }
 
+// Show - An episode. Daily shows' episodes named by the date they aired have
+// Aired (YYYY-MM-DD) set instead of a season and episode, and fansub releases
+// are marked Anime, as their episodes are numbered from the start of the show.
 type Show struct {
-	Name    string `json:"name"`
-	Season  int    `json:"season"`
-	Episode int    `json:"episode"`
-	Title   string `json:"title"`
-	Ext     string `json:"ext"`
+	Name         string  `json:"name"`
+	Season       int     `json:"season"`
+	Episode      int     `json:"episode"`
+	Title        string  `json:"title"`
+	Ext          string  `json:"ext"`
+	SeriesImdbID string  `json:"seriesImdbId,omitempty"`
+	SeriesPoster string  `json:"seriesPoster,omitempty"`
+	SeasonPoster string  `json:"seasonPoster,omitempty"`
+	Aired        string  `json:"aired,omitempty"`
+	Anime        bool    `json:"anime,omitempty"`
+	Release      Release `json:"release"`
+	Metadata
 }
 
 func (s *Show) IsMovie() bool {
@@ -81,10 +167,26 @@ func (s *Show) IsValid() bool {
 	return s.Name != ""
 }
 
+func (s *Show) GetName() string {
+	return s.Name
+}
+
 func (s *Show) SetExt(ext string) {
 	s.Ext = ext
 }
 
+func (s *Show) GetExt() string {
+	return s.Ext
+}
+
+func (s *Show) GetMetadata() *Metadata {
+	return &s.Metadata
+}
+
+func (s *Show) GetRelease() *Release {
+	return &s.Release
+}
+
 func (s *Show) GetNewName() string {
 	config := config.GetConfig()
 
@@ -107,6 +209,37 @@ func (s *Show) GetNewPath() string {
 	return util.JoinPaths(path, s.GetNewName())
 }
 
+// GetShowDirectory - Returns the folder of the show an episode was moved into,
+// which is the first directory below the shows directory
+func GetShowDirectory(episodePath string) string {
+	showsDir := config.GetConfig().DefaultDirectories.Shows
+
+	rel, err := filepath.Rel(showsDir, filepath.Dir(episodePath))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return filepath.Dir(episodePath)
+	}
+
+	return filepath.Join(showsDir, strings.Split(rel, string(filepath.Separator))[0])
+}
+
+var airDatePattern = regexp.MustCompile(`\b((?:19|20)[0-9]{2})[. _-]([0-9]{2})[. _-]([0-9]{2})\b`)
+
+// parseAirDate - Returns the date (YYYY-MM-DD) in the name of a daily show's
+// episode, or an empty string if there is none
+func parseAirDate(name string) string {
+	parts := airDatePattern.FindStringSubmatch(name)
+	if parts == nil {
+		return ""
+	}
+
+	aired, err := time.Parse("2006-01-02", strings.Join(parts[1:], "-"))
+	if err != nil {
+		return ""
+	}
+
+	return aired.Format("2006-01-02")
+}
+
 func ParseTorrentName(name string) (Video, error) {
 	var ret Video
 	parsed, err := torrentParser.Parse(name)
@@ -114,7 +247,7 @@ func ParseTorrentName(name string) (Video, error) {
 		return ret, err
 	}
 
-	if parsed.Title[len(parsed.Title)-2:] == " -" {
+	if strings.HasSuffix(parsed.Title, " -") {
 		parsed.Title = strings.ReplaceAll(parsed.Title, " -", "")
 	}
 
@@ -122,16 +255,46 @@ func ParseTorrentName(name string) (Video, error) {
 
 	parsed.Title = util.CapitalizeFirstAll(parsed.Title)
 
-	if parsed.Season == 0 {
+	release := Release{
+		Resolution: parsed.Resolution,
+		Source:     parsed.Quality,
+		Codec:      parsed.Codec,
+		Audio:      parsed.Audio,
+		Proper:     parsed.Proper,
+		Repack:     parsed.Repack,
+	}
+
+	aired := parseAirDate(name)
+
+	switch {
+	case parsed.Season == 0 && aired != "":
+		ret = &Show{
+			Name:    parsed.Title,
+			Aired:   aired,
+			Release: release,
+		}
+	case parsed.Season == 0 && parsed.Website != "" && parsed.Episode != 0:
+		// Fansub releases start with the group in brackets and only number
+		// the episode, e.g. "[Group] Show - 05"
+		ret = &Show{
+			Name:    parsed.Title,
+			Season:  1,
+			Episode: parsed.Episode,
+			Anime:   true,
+			Release: release,
+		}
+	case parsed.Season == 0:
 		ret = &Movie{
-			Name: parsed.Title,
-			Year: parsed.Year,
+			Name:    parsed.Title,
+			Year:    parsed.Year,
+			Release: release,
 		}
-	} else {
+	default:
 		ret = &Show{
 			Name:    parsed.Title,
 			Season:  parsed.Season,
 			Episode: parsed.Episode,
+			Release: release,
 		}
 	}
 
@@ -0,0 +1,127 @@
+package artwork
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/fetch"
+	"torrentRenamer/util"
+)
+
+// image - An image URL and the file it is saved to, without the extension
+type image struct {
+	URL  string
+	Path string
+}
+
+// imageExt - Returns the extension of the image URL, .jpg if it has none
+func imageExt(imageURL string) string {
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return ".jpg"
+	}
+
+	switch ext := strings.ToLower(path.Ext(parsed.Path)); ext {
+	case ".png", ".jpg":
+		return ext
+	}
+
+	return ".jpg"
+}
+
+func download(img image, force bool) error {
+	dest := img.Path + imageExt(img.URL)
+
+	if !force {
+		if _, err := os.Stat(dest); err == nil {
+			return nil
+		}
+	}
+
+	data, err := fetch.Get(img.URL)
+	if err != nil {
+		return err
+	}
+
+	return util.WriteFile(dest, data)
+}
+
+func basePath(videoPath string) string {
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+}
+
+// movieImages - Movies in their own folder get poster.jpg and fanart.jpg,
+// otherwise the images are prefixed with the video's name so several movies can
+// share a folder
+func movieImages(moviePath string, m *torrentRenamer.Movie) []image {
+	dir := filepath.Dir(moviePath)
+	prefix := dir + string(filepath.Separator)
+
+	if filepath.Clean(dir) == filepath.Clean(config.GetConfig().DefaultDirectories.Movies) {
+		prefix = basePath(moviePath) + "-"
+	}
+
+	return []image{
+		{URL: m.Poster, Path: prefix + "poster"},
+		{URL: m.Fanart, Path: prefix + "fanart"},
+	}
+}
+
+func seasonPosterName(season int) string {
+	if season == 0 {
+		return "season-specials-poster"
+	}
+
+	return fmt.Sprintf("season%02d-poster", season)
+}
+
+// showImages - The show's poster, fanart and season posters go into the show's
+// folder, and the episode's own image (a still) is saved as its thumbnail. Only
+// plugins fill in fanart and season posters, images without a URL are skipped.
+func showImages(episodePath string, s *torrentRenamer.Show) []image {
+	showDir := torrentRenamer.GetShowDirectory(episodePath)
+
+	return []image{
+		{URL: s.SeriesPoster, Path: filepath.Join(showDir, "poster")},
+		{URL: s.Fanart, Path: filepath.Join(showDir, "fanart")},
+		{URL: s.SeasonPoster, Path: filepath.Join(showDir, seasonPosterName(s.Season))},
+		{URL: s.Poster, Path: basePath(episodePath) + "-thumb"},
+	}
+}
+
+// Download - Saves the artwork of a video found by a service next to where it
+// was moved, using the names Plex, Jellyfin and Kodi look for. Existing images
+// are kept unless force is set.
+func Download(videoPath string, video torrentRenamer.Video, force bool) error {
+	var images []image
+
+	switch v := video.(type) {
+	case *torrentRenamer.Movie:
+		images = movieImages(videoPath, v)
+	case *torrentRenamer.Show:
+		images = showImages(videoPath, v)
+	}
+
+	failed := make([]string, 0)
+
+	for _, img := range images {
+		if img.URL == "" {
+			continue
+		}
+
+		if err := download(img, force); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", filepath.Base(img.Path), err.Error()))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Could not download %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"torrentRenamer/logger"
 	"torrentRenamer/util"
 
 	flag "github.com/spf13/pflag"
@@ -13,6 +14,8 @@ import (
 
 const (
 	configLocationTemplate = "{{home}}/.torrentRenamerrc"
+	imdbIndexTemplate      = "{{home}}/.torrentRenamer/imdb.idx"
+	libraryIndexTemplate   = "{{home}}/.torrentRenamer/library.json"
 )
 
 type renameTemplates struct {
@@ -27,31 +30,138 @@ type videoDirectories struct {
 
 type service struct {
 	ApiKey          string          `json:"apiKey"`
+	BaseUrl         string          `json:"baseUrl"`
+	RenameTemplates renameTemplates `json:"renameTemplates"`
+}
+
+type imdbService struct {
+	IndexPath       string          `json:"indexPath"`
 	RenameTemplates renameTemplates `json:"renameTemplates"`
 }
 
 type services struct {
-	Omdb service `json:"omdb"`
+	Omdb service     `json:"omdb"`
+	Imdb imdbService `json:"imdb"`
+}
+
+type matching struct {
+	Threshold     float64 `json:"threshold"`
+	YearTolerance int     `json:"yearTolerance"`
+}
+
+type plugin struct {
+	Name            string          `json:"name"`
+	Command         string          `json:"command"`
+	Args            []string        `json:"args"`
+	Capabilities    []string        `json:"capabilities"`
+	Timeout         int             `json:"timeout"`
+	RenameTemplates renameTemplates `json:"renameTemplates"`
 }
 
 type conversion struct {
-	AutoConvert  bool   `json:"autoConvert"`
-	Format       string `json:"format"`
-	Converter    string `json:"converter"`
-	ArgsTemplate string `json:"commandTemplate"`
+	AutoConvert   bool     `json:"autoConvert"`
+	Format        string   `json:"format"`
+	Converter     string   `json:"converter"`
+	ArgsTemplate  string   `json:"commandTemplate"`
+	RemuxTemplate string   `json:"remuxTemplate"`
+	Probe         bool     `json:"probe"`
+	VideoCodecs   []string `json:"videoCodecs"`
+	AudioCodecs   []string `json:"audioCodecs"`
+}
+
+type network struct {
+	Timeout           int                `json:"timeout"`
+	MaxRetries        int                `json:"maxRetries"`
+	RequestsPerSecond float64            `json:"requestsPerSecond"`
+	HostRateLimits    map[string]float64 `json:"hostRateLimits"`
+	CaBundle          string             `json:"caBundle"`
+	Proxy             string             `json:"proxy"`
+	UserAgent         string             `json:"userAgent"`
+	FixtureMode       string             `json:"fixtureMode"`
+	FixtureDir        string             `json:"fixtureDir"`
+}
+
+type mediaServer struct {
+	Name         string            `json:"name"`
+	Type         string            `json:"type"`
+	Url          string            `json:"url"`
+	Token        string            `json:"token"`
+	PathMappings map[string]string `json:"pathMappings"`
+}
+
+type notifier struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Url          string   `json:"url"`
+	Token        string   `json:"token"`
+	Username     string   `json:"username"`
+	Password     string   `json:"password"`
+	From         string   `json:"from"`
+	To           []string `json:"to"`
+	OnlyFailures bool     `json:"onlyFailures"`
+}
+
+type logging struct {
+	Level     string `json:"level"`
+	File      string `json:"file"`
+	FileLevel string `json:"fileLevel"`
+	MaxSize   int    `json:"maxSize"`
+	MaxFiles  int    `json:"maxFiles"`
+}
+
+type library struct {
+	Enabled   bool   `json:"enabled"`
+	IndexPath string `json:"indexPath"`
+	Checksums bool   `json:"checksums"`
+}
+
+type upgrades struct {
+	Enabled      bool     `json:"enabled"`
+	ArchiveDir   string   `json:"archiveDir"`
+	Resolutions  []string `json:"resolutions"`
+	Sources      []string `json:"sources"`
+	Codecs       []string `json:"codecs"`
+	PreferProper bool     `json:"preferProper"`
+	Size         string   `json:"size"`
+}
+
+type hook struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+type metadataFiles struct {
+	Nfo          bool `json:"nfo"`
+	Artwork      bool `json:"artwork"`
+	ForceArtwork bool `json:"forceArtwork"`
+	Tags         bool `json:"tags"`
 }
 
 type Config struct {
 	DefaultDirectories  videoDirectories  `json:"defaultDirectories"`
 	Services            services          `json:"services"`
 	DefaultService      string            `json:"defaultService"`
+	Language            string            `json:"language"`
+	Region              string            `json:"region"`
 	RenameTemplates     renameTemplates   `json:"renameTemplates"`
 	Conversion          conversion        `json:"conversion"`
 	RenameOverrides     map[string]string `json:"renameOverrides"`
+	IdOverrides         map[string]string `json:"idOverrides"`
+	Plugins             []plugin          `json:"plugins"`
+	Network             network           `json:"network"`
+	Matching            matching          `json:"matching"`
+	MetadataFiles       metadataFiles     `json:"metadataFiles"`
+	MediaServers        []mediaServer     `json:"mediaServers"`
+	Hooks               map[string][]hook `json:"hooks"`
+	Notifiers           []notifier        `json:"notifiers"`
+	Output              string            `json:"output"`
+	Log                 logging           `json:"log"`
+	Library             library           `json:"library"`
+	Upgrades            upgrades          `json:"upgrades"`
 	RenameWithoutPrompt bool
 }
 
-var config Config
+var config = getDefaultConfig()
 
 func getConfigLocation() (string, error) {
 	homeDir, err := util.GetUserHomeDirectory()
@@ -80,8 +190,11 @@ func configFileExists() bool {
 	return true
 }
 
-func loadConfigFile() (Config, error) {
-	conf := Config{}
+// loadConfigFile - Reads the config file over the defaults, so settings that are
+// missing from it, e.g. because they were added after it was saved, keep their
+// defaults
+func loadConfigFile(defaults Config) (Config, error) {
+	conf := defaults
 
 	configLocation, err := getConfigLocation()
 	if err != nil {
@@ -100,17 +213,92 @@ func GetConfig() *Config {
 	return &config
 }
 
-func saveConfig() {
-	var configLocation string
+func saveConfig() error {
 	conf := GetConfig()
 
 	bytes, err := json.MarshalIndent(conf, "", "\t")
-	if err == nil {
-		configLocation, err = getConfigLocation()
-		if err == nil {
-			ioutil.WriteFile(configLocation, bytes, 0644)
+	if err != nil {
+		return err
+	}
+
+	configLocation, err := getConfigLocation()
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(configLocation, bytes, 0644); err != nil {
+		return err
+	}
+
+	logger.Infof("Saved config to %s", configLocation)
+
+	return nil
+}
+
+// validateConfig - Returns an error listing the allowed values of the first
+// setting with a value that is not one of them
+func validateConfig() error {
+	if config.Output != "" && config.Output != "text" && config.Output != "json" {
+		return fmt.Errorf("Unknown output %s, expected text or json", config.Output)
+	}
+
+	if config.Upgrades.Size != "" && config.Upgrades.Size != "larger" && config.Upgrades.Size != "smaller" {
+		return fmt.Errorf("Unknown size preference %s, expected larger or smaller", config.Upgrades.Size)
+	}
+
+	for _, level := range []string{config.Log.Level, config.Log.FileLevel} {
+		if level == "" {
+			continue
+		}
+
+		if _, err := logger.ParseLevel(level); err != nil {
+			return err
 		}
 	}
+
+	return nil
+}
+
+// parseLevel - Returns the level with the name, which was validated already,
+// or info if it is empty
+func parseLevel(name string) logger.Level {
+	if name == "" {
+		return logger.LevelInfo
+	}
+
+	level, _ := logger.ParseLevel(name)
+
+	return level
+}
+
+// setupLogger - Applies the log settings, -v and -q win over the log level of
+// the console but not over the one of the log file
+func setupLogger(verbose bool, quiet bool) {
+	level := parseLevel(config.Log.Level)
+
+	if quiet {
+		level = logger.LevelError
+	}
+
+	if verbose {
+		level = logger.LevelDebug
+	}
+
+	logger.SetLevel(level)
+	logger.SetFileLevel(parseLevel(config.Log.FileLevel))
+
+	if config.Log.File == "" {
+		return
+	}
+
+	maxSize := config.Log.MaxSize
+	if maxSize <= 0 {
+		maxSize = 10
+	}
+
+	if err := logger.SetFile(config.Log.File, int64(maxSize)*1024*1024, config.Log.MaxFiles); err != nil {
+		logger.Errorf("Could not open log file %s: %s", config.Log.File, err.Error())
+	}
 }
 
 func addRenameOverride(override []string) bool {
@@ -138,49 +326,164 @@ func removeRenameOverride(override string) bool {
 	return true
 }
 
-func init() {
-	var defaultConfig Config
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return fallback
+}
+
+func idOverrideKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func addIdOverride(override []string) bool {
+	if len(override) != 2 {
+		return false
+	}
+
+	conf := GetConfig()
+	if conf.IdOverrides == nil {
+		conf.IdOverrides = make(map[string]string)
+	}
+
+	conf.IdOverrides[idOverrideKey(override[0])] = strings.TrimSpace(override[1])
+
+	return true
+}
+
+func removeIdOverride(override string) bool {
+	if override == "" {
+		return false
+	}
+
+	conf := GetConfig()
+
+	if _, ok := conf.IdOverrides[idOverrideKey(override)]; ok {
+		delete(conf.IdOverrides, idOverrideKey(override))
+	}
+
+	return true
+}
+
+// getDefaultConfig - Returns the settings used when there is no config file,
+// and for the settings missing from it
+func getDefaultConfig() Config {
+	userHomeDir, err := util.GetUserHomeDirectory()
+	if err != nil {
+		panic("Could not get current user information")
+	}
+
+	imdbIndex, err := util.InsertTemplateData(imdbIndexTemplate, nil)
+	if err != nil {
+		panic(fmt.Errorf("Could not get IMDb index location: %e", err))
+	}
+
+	libraryIndex, err := util.InsertTemplateData(libraryIndexTemplate, nil)
+	if err != nil {
+		panic(fmt.Errorf("Could not get library index location: %e", err))
+	}
+
+	return Config{
+		DefaultDirectories: videoDirectories{
+			Movies: util.JoinPaths(userHomeDir, "Videos", "Movies"),
+			Shows:  util.JoinPaths(userHomeDir, "Videos", "TV Shows"),
+		},
+		Services: services{
+			Omdb: service{
+				ApiKey:  "",
+				BaseUrl: "https://www.omdbapi.com/",
+				RenameTemplates: renameTemplates{
+					Movies: "{{ .Name }} ({{ .Year }}).{{ .Ext }}",
+					Shows:  "{{ .Name }}{{sep}}{{ .Name }} - Season {{padDigit .Season 2}}{{sep}}{{ .Name }} - S{{padDigit .Season 2 }}E{{padDigit .Episode 2 }} - {{ .Title }}.{{ .Ext }}",
+				},
+			},
+			Imdb: imdbService{
+				IndexPath: imdbIndex,
+				RenameTemplates: renameTemplates{
+					Movies: "{{ .Name }} ({{ .Year }}).{{ .Ext }}",
+					Shows:  "{{ .Name }}{{sep}}{{ .Name }} - Season {{padDigit .Season 2}}{{sep}}{{ .Name }} - S{{padDigit .Season 2 }}E{{padDigit .Episode 2 }} - {{ .Title }}.{{ .Ext }}",
+				},
+			},
+		},
+		DefaultService: "OMDB",
+		RenameTemplates: renameTemplates{
+			Movies: "{{ .Name }} ({{ .Year }}).{{ .Ext }}",
+			Shows:  "{{ .Name }}{{sep}}{{ .Name }} - Season {{padDigit .Season 2}}{{sep}}{{ .Name }} - S{{padDigit .Season 2 }}E{{padDigit .Episode 2 }}.{{ .Ext }}",
+		},
+		Conversion: conversion{
+			AutoConvert:   false,
+			Format:        "mkv",
+			Converter:     "ffmpeg",
+			ArgsTemplate:  "-i \"{{escapeSpaces .Old }}\"{{ if .VideoCodec }} -c:v {{ .VideoCodec }}{{ end }}{{ if .AudioCodec }} -c:a {{ .AudioCodec }}{{ end }} \"{{escapeSpaces .New }}\"",
+			RemuxTemplate: "-i \"{{escapeSpaces .Old }}\" -map 0 -c copy \"{{escapeSpaces .New }}\"",
+			Probe:         true,
+			VideoCodecs:   make([]string, 0),
+			AudioCodecs:   make([]string, 0),
+		},
+		RenameOverrides: make(map[string]string),
+		IdOverrides:     make(map[string]string),
+		Plugins:         make([]plugin, 0),
+		Network: network{
+			Timeout:           15,
+			MaxRetries:        3,
+			RequestsPerSecond: 5,
+			HostRateLimits:    make(map[string]float64),
+			UserAgent:         "torrentRenamer",
+			FixtureMode:       "live",
+			FixtureDir:        "fixtures",
+		},
+		Matching: matching{
+			Threshold:     0.85,
+			YearTolerance: 1,
+		},
+		MetadataFiles: metadataFiles{
+			Nfo: true,
+		},
+		MediaServers: make([]mediaServer, 0),
+		Hooks:        make(map[string][]hook),
+		Notifiers:    make([]notifier, 0),
+		Output:       "text",
+		Log: logging{
+			Level:     "info",
+			FileLevel: "info",
+			MaxSize:   10,
+			MaxFiles:  3,
+		},
+		Library: library{
+			Enabled:   true,
+			IndexPath: libraryIndex,
+		},
+		Upgrades: upgrades{
+			ArchiveDir:   util.JoinPaths(userHomeDir, "Videos", "Archive"),
+			Resolutions:  []string{"2160p", "1080p", "720p", "576p", "480p"},
+			Sources:      []string{"BluRay", "WEB-DL", "WEBRip", "BDRip", "BRRip", "HDTV", "DVDRip", "HDRip", "DVDScr", "TS", "CAM"},
+			Codecs:       []string{"x265", "h265", "x264", "h264", "xvid"},
+			PreferProper: true,
+			Size:         "larger",
+		},
+	}
+}
+
+// Init - Reads the config file and the flags over the defaults, which are the
+// config until then. Exits after saving the config or when there is nothing to
+// do.
+func Init() {
+	defaultConfig := getDefaultConfig()
 
 	if configFileExists() {
-		userConfig, err := loadConfigFile()
+		userConfig, err := loadConfigFile(defaultConfig)
 		if err != nil {
 			panic(fmt.Errorf("Could not get config from file: %e", err))
 		}
 
-		defaultConfig = userConfig
-	} else {
-		userHomeDir, err := util.GetUserHomeDirectory()
-		if err != nil {
-			panic("Could not get current user information")
+		// An empty template cannot remux anything
+		if userConfig.Conversion.RemuxTemplate == "" {
+			userConfig.Conversion.RemuxTemplate = defaultConfig.Conversion.RemuxTemplate
 		}
 
-		defaultConfig = Config{
-			DefaultDirectories: videoDirectories{
-				Movies: util.JoinPaths(userHomeDir, "Videos", "Movies"),
-				Shows:  util.JoinPaths(userHomeDir, "Videos", "TV Shows"),
-			},
-			Services: services{
-				Omdb: service{
-					ApiKey: "",
-					RenameTemplates: renameTemplates{
-						Movies: "{{ .Name }} ({{ .Year }}).{{ .Ext }}",
-						Shows:  "{{ .Name }}{{sep}}{{ .Name }} - Season {{padDigit .Season 2}}{{sep}}{{ .Name }} - S{{padDigit .Season 2 }}E{{padDigit .Episode 2 }} - {{ .Title }}.{{ .Ext }}",
-					},
-				},
-			},
-			DefaultService: "OMDB",
-			RenameTemplates: renameTemplates{
-				Movies: "{{ .Name }} ({{ .Year }}).{{ .Ext }}",
-				Shows:  "{{ .Name }}{{sep}}{{ .Name }} - Season {{padDigit .Season 2}}{{sep}}{{ .Name }} - S{{padDigit .Season 2 }}E{{padDigit .Episode 2 }}.{{ .Ext }}",
-			},
-			Conversion: conversion{
-				AutoConvert:  false,
-				Format:       "mkv",
-				Converter:    "ffmpeg",
-				ArgsTemplate: "-i \"{{escapeSpaces .Old }}\" \"{{escapeSpaces .New }}\"",
-			},
-			RenameOverrides: make(map[string]string),
-		}
+		defaultConfig = userConfig
 	}
 
 	// Default Directories
@@ -189,10 +492,17 @@ func init() {
 
 	// Services
 	omdbApiKey := flag.String("omdb-key", defaultConfig.Services.Omdb.ApiKey, "Your OMDB API key")
+	omdbURL := flag.String("omdb-url", defaultConfig.Services.Omdb.BaseUrl, "The base URL of the OMDB API, e.g. to use a local mirror")
 	omdbMovieTemplate := flag.String("omdb-movie-template", defaultConfig.Services.Omdb.RenameTemplates.Movies, "How you would like to rename movies with data from OMDB")
 	omdbShowTempalte := flag.String("omdb-show-template", defaultConfig.Services.Omdb.RenameTemplates.Shows, "How you would like to rename shows with data from OMDB")
 
+	imdbIndexPath := flag.String("imdb-index", defaultConfig.Services.Imdb.IndexPath, "Where the index built from the IMDb dataset dumps is stored")
+	imdbMovieTemplate := flag.String("imdb-movie-template", defaultConfig.Services.Imdb.RenameTemplates.Movies, "How you would like to rename movies with data from the IMDb datasets")
+	imdbShowTemplate := flag.String("imdb-show-template", defaultConfig.Services.Imdb.RenameTemplates.Shows, "How you would like to rename shows with data from the IMDb datasets")
+
 	defaultService := flag.String("service", defaultConfig.DefaultService, "The default service to use for video lookup")
+	language := flag.String("language", defaultConfig.Language, "The language (e.g. de) localized titles are looked up in, for services that support it")
+	region := flag.String("region", defaultConfig.Region, "The region (e.g. DE) localized titles are looked up for, for services that support it")
 
 	// Default rename templates
 	movieTemplate := flag.String("movie-template", defaultConfig.RenameTemplates.Movies, "How you would like to rename movies")
@@ -202,11 +512,63 @@ func init() {
 	autoConvert := flag.BoolP("auto-convert", "a", defaultConfig.Conversion.AutoConvert, "Whether or not to attempt to auto-convert video file")
 	convertFormat := flag.StringP("convert-format", "f", defaultConfig.Conversion.Format, "The format to which you'd like to auto-convert the video file")
 	convertConverter := flag.StringP("converter", "c", defaultConfig.Conversion.Converter, "The program (command) used to run the video conversion")
-	convertArgsTemplate := flag.String("convert-args", defaultConfig.Conversion.ArgsTemplate, "The Golang template for args passed to the converter")
+	convertArgsTemplate := flag.String("convert-args", defaultConfig.Conversion.ArgsTemplate, "The Golang template for args passed to the converter, which can use .VideoCodec and .AudioCodec, the first allowed codecs")
+	remuxArgsTemplate := flag.String("remux-args", defaultConfig.Conversion.RemuxTemplate, "The Golang template for args passed to the converter to only change the container")
+	probe := flag.Bool("probe", defaultConfig.Conversion.Probe, "Inspect videos with ffprobe to decide whether they need to be remuxed or transcoded")
+	videoCodecs := flag.StringSlice("video-codecs", defaultConfig.Conversion.VideoCodecs, "The video codecs (e.g. h264, hevc) that are kept, empty to keep any")
+	audioCodecs := flag.StringSlice("audio-codecs", defaultConfig.Conversion.AudioCodecs, "The audio codecs (e.g. aac, ac3) that are kept, empty to keep any")
+
+	// Network
+	timeout := flag.Int("timeout", defaultConfig.Network.Timeout, "Seconds to wait for a single HTTP request before giving up")
+	maxRetries := flag.Int("max-retries", defaultConfig.Network.MaxRetries, "How many times to retry rate limited or failed HTTP requests")
+	rateLimit := flag.Float64("rate-limit", defaultConfig.Network.RequestsPerSecond, "The maximum number of HTTP requests per second sent to a single host")
+	caBundle := flag.String("ca-bundle", defaultConfig.Network.CaBundle, "A PEM file with additional certificate authorities to trust")
+	proxy := flag.String("proxy", defaultConfig.Network.Proxy, "The proxy URL for HTTP requests (defaults to the HTTP_PROXY/HTTPS_PROXY environment variables)")
+	userAgent := flag.String("user-agent", defaultConfig.Network.UserAgent, "The User-Agent header sent with HTTP requests")
+	fixtureMode := flag.String("fetch-mode", getEnv("TORRENTRENAMER_FETCH_MODE", defaultConfig.Network.FixtureMode), "live, record (save HTTP responses as fixtures) or replay (answer HTTP requests from fixtures)")
+	fixtureDir := flag.String("fixtures", getEnv("TORRENTRENAMER_FIXTURES", defaultConfig.Network.FixtureDir), "The directory where HTTP fixtures are recorded to and replayed from")
+
+	// Matching
+	matchThreshold := flag.Float64("match-threshold", defaultConfig.Matching.Threshold, "How similar (0 to 1) a service's title has to be to the parsed one to be accepted")
+	yearTolerance := flag.Int("year-tolerance", defaultConfig.Matching.YearTolerance, "How many years a service's release year may differ from the parsed one")
 
 	// Rename override options
 	addOverride := flag.StringSlice("add-override", []string{}, "Add an override to parsed names")
 	removeOverride := flag.String("rm-override", "", "Remove an override from parsed names")
+	addPin := flag.StringSlice("add-id-override", []string{}, "Pin a parsed name to an exact metadata ID (e.g. an IMDb ID)")
+	removePin := flag.String("rm-id-override", "", "Remove a metadata ID pin from a parsed name")
+
+	// Metadata files
+	writeNfo := flag.Bool("nfo", defaultConfig.MetadataFiles.Nfo, "Write Kodi/Jellyfin NFO files next to videos found by a service")
+	artwork := flag.Bool("artwork", defaultConfig.MetadataFiles.Artwork, "Download the posters and episode stills of videos found by a service, and the fanart and season posters only plugins provide")
+	forceArtwork := flag.Bool("force-artwork", defaultConfig.MetadataFiles.ForceArtwork, "Replace artwork that was already downloaded")
+	writeTags := flag.Bool("tags", defaultConfig.MetadataFiles.Tags, "Write the title, show, season, episode and plot into MKV and MP4 files")
+
+	// Output
+	output := flag.String("output", defaultConfig.Output, "text, or json for a stream of JSON events (one per line) instead of messages")
+
+	// Logging
+	logLevel := flag.String("log-level", defaultConfig.Log.Level, "Only show messages of this level or above: debug, info, warn or error")
+	verbose := flag.BoolP("verbose", "v", false, "Show debug messages (same as --log-level=debug)")
+	quiet := flag.BoolP("quiet", "q", false, "Only show errors (same as --log-level=error)")
+	logFile := flag.String("log-file", defaultConfig.Log.File, "A file that messages are written to as well, with timestamps")
+	logFileLevel := flag.String("log-file-level", defaultConfig.Log.FileLevel, "Only write messages of this level or above to the log file, regardless of -v and -q")
+	logMaxSize := flag.Int("log-max-size", defaultConfig.Log.MaxSize, "The size in MB at which the log file is rotated")
+	logMaxFiles := flag.Int("log-max-files", defaultConfig.Log.MaxFiles, "How many rotated log files are kept")
+
+	// Library
+	libraryEnabled := flag.Bool("library", defaultConfig.Library.Enabled, "Record moved videos in the library index")
+	libraryIndexPath := flag.String("library-index", defaultConfig.Library.IndexPath, "Where the index of the videos in your library is stored")
+	libraryChecksums := flag.Bool("library-checksums", defaultConfig.Library.Checksums, "Store a SHA-256 checksum of every video in the library index (reads the whole file)")
+
+	// Upgrades
+	upgrade := flag.Bool("upgrade", defaultConfig.Upgrades.Enabled, "Replace videos in the library with better copies, and skip copies that are not better")
+	archiveDir := flag.String("archive-dir", defaultConfig.Upgrades.ArchiveDir, "Where replaced videos are moved to")
+	preferResolutions := flag.StringSlice("prefer-resolutions", defaultConfig.Upgrades.Resolutions, "Resolutions from best to worst")
+	preferSources := flag.StringSlice("prefer-sources", defaultConfig.Upgrades.Sources, "Sources (e.g. BluRay, WEB-DL) from best to worst")
+	preferCodecs := flag.StringSlice("prefer-codecs", defaultConfig.Upgrades.Codecs, "Codecs from best to worst")
+	preferProper := flag.Bool("prefer-proper", defaultConfig.Upgrades.PreferProper, "Prefer PROPER and REPACK releases over otherwise equal ones")
+	preferSize := flag.String("prefer-size", defaultConfig.Upgrades.Size, "Whether larger or smaller files are better when everything else is equal, empty to ignore the size")
 
 	// Save config
 	save := flag.Bool("save-config", false, "Saves your specified config as default")
@@ -223,28 +585,97 @@ func init() {
 		},
 		Services: services{
 			Omdb: service{
-				ApiKey: *omdbApiKey,
+				ApiKey:  *omdbApiKey,
+				BaseUrl: *omdbURL,
 				RenameTemplates: renameTemplates{
 					Movies: *omdbMovieTemplate,
 					Shows:  *omdbShowTempalte,
 				},
 			},
+			Imdb: imdbService{
+				IndexPath: *imdbIndexPath,
+				RenameTemplates: renameTemplates{
+					Movies: *imdbMovieTemplate,
+					Shows:  *imdbShowTemplate,
+				},
+			},
 		},
 		DefaultService: *defaultService,
+		Language:       *language,
+		Region:         *region,
 		RenameTemplates: renameTemplates{
 			Movies: *movieTemplate,
 			Shows:  *showTempalte,
 		},
 		Conversion: conversion{
-			AutoConvert:  *autoConvert,
-			Format:       *convertFormat,
-			Converter:    *convertConverter,
-			ArgsTemplate: *convertArgsTemplate,
+			AutoConvert:   *autoConvert,
+			Format:        *convertFormat,
+			Converter:     *convertConverter,
+			ArgsTemplate:  *convertArgsTemplate,
+			RemuxTemplate: *remuxArgsTemplate,
+			Probe:         *probe,
+			VideoCodecs:   *videoCodecs,
+			AudioCodecs:   *audioCodecs,
+		},
+		RenameOverrides: defaultConfig.RenameOverrides,
+		IdOverrides:     defaultConfig.IdOverrides,
+		Plugins:         defaultConfig.Plugins,
+		Network: network{
+			Timeout:           *timeout,
+			MaxRetries:        *maxRetries,
+			RequestsPerSecond: *rateLimit,
+			HostRateLimits:    defaultConfig.Network.HostRateLimits,
+			CaBundle:          *caBundle,
+			Proxy:             *proxy,
+			UserAgent:         *userAgent,
+			FixtureMode:       *fixtureMode,
+			FixtureDir:        *fixtureDir,
+		},
+		Matching: matching{
+			Threshold:     *matchThreshold,
+			YearTolerance: *yearTolerance,
+		},
+		MetadataFiles: metadataFiles{
+			Nfo:          *writeNfo,
+			Artwork:      *artwork,
+			ForceArtwork: *forceArtwork,
+			Tags:         *writeTags,
+		},
+		MediaServers: defaultConfig.MediaServers,
+		Hooks:        defaultConfig.Hooks,
+		Notifiers:    defaultConfig.Notifiers,
+		Output:       *output,
+		Log: logging{
+			Level:     *logLevel,
+			File:      *logFile,
+			FileLevel: *logFileLevel,
+			MaxSize:   *logMaxSize,
+			MaxFiles:  *logMaxFiles,
+		},
+		Library: library{
+			Enabled:   *libraryEnabled,
+			IndexPath: *libraryIndexPath,
+			Checksums: *libraryChecksums,
+		},
+		Upgrades: upgrades{
+			Enabled:      *upgrade,
+			ArchiveDir:   *archiveDir,
+			Resolutions:  *preferResolutions,
+			Sources:      *preferSources,
+			Codecs:       *preferCodecs,
+			PreferProper: *preferProper,
+			Size:         *preferSize,
 		},
-		RenameOverrides:     defaultConfig.RenameOverrides,
 		RenameWithoutPrompt: *rename,
 	}
 
+	if err := validateConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	setupLogger(*verbose, *quiet)
+
 	exit := false
 
 	if len(*addOverride) == 2 {
@@ -263,8 +694,26 @@ func init() {
 		}
 	}
 
+	if len(*addPin) == 2 {
+		change := addIdOverride(*addPin)
+
+		if change {
+			*save = true
+		}
+	}
+
+	if *removePin != "" {
+		change := removeIdOverride(*removePin)
+
+		if change {
+			*save = true
+		}
+	}
+
 	if *save {
-		saveConfig()
+		if err := saveConfig(); err != nil {
+			logger.Errorf("Could not save config: %s", err.Error())
+		}
 	}
 
 	if flag.NArg() < 1 {
@@ -288,13 +737,30 @@ func ApplyRenameOverrides(str string) string {
 	config := GetConfig()
 	lowStr := strings.ToLower(str)
 
+	matched := false
+
 	for key, value := range config.RenameOverrides {
 		lowKey := strings.ToLower(key)
 
 		if strings.Contains(lowStr, lowKey) {
+			logger.Debugf("Rename override \"%s\" renames \"%s\" to \"%s\"", key, str, util.CapitalizeFirstAll(value))
 			str = util.CapitalizeFirstAll(value)
+			matched = true
 		}
 	}
 
+	if !matched && len(config.RenameOverrides) > 0 {
+		logger.Debugf("No rename override matches \"%s\"", str)
+	}
+
 	return str
 }
+
+// GetIdOverride - Returns the metadata ID pinned to the given parsed name. Unlike
+// rename overrides, the whole name has to match (ignoring case).
+func GetIdOverride(name string) (string, bool) {
+	config := GetConfig()
+	id, ok := config.IdOverrides[idOverrideKey(name)]
+
+	return id, ok
+}
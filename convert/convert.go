@@ -0,0 +1,234 @@
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"torrentRenamer/config"
+	"torrentRenamer/exec"
+	"torrentRenamer/logger"
+	"torrentRenamer/probe"
+	"torrentRenamer/util"
+)
+
+const (
+	ActionNone      = "none"
+	ActionRemux     = "remux"
+	ActionTranscode = "transcode"
+)
+
+// probeAvailable and probeVideo - Where Decide looks for and runs ffprobe,
+// which tests replace
+var (
+	probeAvailable = probe.IsAvailable
+	probeVideo     = probe.Probe
+)
+
+// Decision - What has to be done for a video to be in the configured format,
+// and why
+type Decision struct {
+	Action string
+	Reason string
+}
+
+// getFormat - Returns the configured format without a leading dot
+func getFormat() string {
+	return strings.TrimPrefix(strings.ToLower(config.GetConfig().Conversion.Format), ".")
+}
+
+// getExt - Returns the file's extension without the dot
+func getExt(filePath string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), ".")
+}
+
+// GetNewPath - Returns the path of the file in the configured format
+func GetNewPath(filePath string) string {
+	return strings.TrimSuffix(filePath, filepath.Ext(filePath)) + "." + getFormat()
+}
+
+// getDisallowedCodecs - Returns the codecs of the streams that are not in the
+// allowed ones. An empty list allows any codec.
+func getDisallowedCodecs(codecs []string, allowed []string) []string {
+	ret := make([]string, 0)
+	if len(allowed) == 0 {
+		return ret
+	}
+
+	for _, codec := range codecs {
+		found := false
+
+		for _, allowedCodec := range allowed {
+			if strings.EqualFold(codec, allowedCodec) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ret = append(ret, codec)
+		}
+	}
+
+	return ret
+}
+
+// decideByExtension - Without ffprobe, only the extension tells whether the
+// video is in the format, and the streams have to be transcoded to be safe
+func decideByExtension(filePath string) Decision {
+	if ext := getExt(filePath); ext != getFormat() {
+		return Decision{Action: ActionTranscode, Reason: fmt.Sprintf("it is a(n) %s", ext)}
+	}
+
+	return Decision{Action: ActionNone}
+}
+
+// Decide - Inspects the video with ffprobe and decides whether it has to be
+// transcoded, because its codecs are not allowed, remuxed, because only its
+// container differs, or nothing at all
+func Decide(filePath string) Decision {
+	conf := config.GetConfig().Conversion
+
+	if !conf.Probe {
+		return decideByExtension(filePath)
+	}
+
+	if !probeAvailable() {
+		logger.Debugf("ffprobe is not in path, deciding whether to convert %s by its extension", filePath)
+		return decideByExtension(filePath)
+	}
+
+	result, err := probeVideo(filePath)
+	if err != nil {
+		logger.Warnf("%s, deciding whether to convert it by its extension", err.Error())
+		return decideByExtension(filePath)
+	}
+
+	return decideByProbe(filePath, result)
+}
+
+// decideByProbe - Decides by the codecs and container ffprobe found in the video
+func decideByProbe(filePath string, result *probe.Result) Decision {
+	conf := config.GetConfig().Conversion
+
+	disallowed := append(
+		getDisallowedCodecs(result.Codecs(probe.TypeVideo), conf.VideoCodecs),
+		getDisallowedCodecs(result.Codecs(probe.TypeAudio), conf.AudioCodecs)...,
+	)
+
+	if len(disallowed) > 0 {
+		return Decision{Action: ActionTranscode, Reason: fmt.Sprintf("its codecs %s are not allowed", strings.Join(disallowed, ", "))}
+	}
+
+	if !result.IsContainer(getFormat()) {
+		return Decision{Action: ActionRemux, Reason: fmt.Sprintf("its container is %s", result.Format.FormatName)}
+	}
+
+	if ext := getExt(filePath); ext != getFormat() {
+		return Decision{Action: ActionRemux, Reason: fmt.Sprintf("it is a(n) %s", ext)}
+	}
+
+	return Decision{Action: ActionNone}
+}
+
+// firstCodec - Returns the codec a transcode should produce, which is the first
+// allowed one, or an empty string to leave it to the converter
+func firstCodec(allowed []string) string {
+	if len(allowed) == 0 {
+		return ""
+	}
+
+	return allowed[0]
+}
+
+// getArgs - Inserts placeholders into the template before splitting it, so
+// paths with spaces or quotes stay one arg each. Transcodes target the first
+// allowed video and audio codecs.
+func getArgs(argsTemplate string, old string, new string) ([]string, error) {
+	conf := config.GetConfig().Conversion
+
+	args, err := util.InsertTemplateData(argsTemplate, struct {
+		Old        string
+		New        string
+		VideoCodec string
+		AudioCodec string
+	}{Old: "TR-OLD", New: "TR-NEW", VideoCodec: firstCodec(conf.VideoCodecs), AudioCodec: firstCodec(conf.AudioCodecs)})
+	if err != nil {
+		return nil, err
+	}
+
+	splitArgs := util.SplitArgs(args)
+
+	for i, arg := range splitArgs {
+		splitArgs[i] = strings.Replace(strings.Replace(arg, "TR-OLD", old, -1), "TR-NEW", new, -1)
+	}
+
+	return splitArgs, nil
+}
+
+func run(argsTemplate string, old string, new string) error {
+	converter := config.GetConfig().Conversion.Converter
+
+	args, err := getArgs(argsTemplate, old, new)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Executing command: %s %s", converter, strings.Join(args, " "))
+	return exec.ExecuteCommandWithSTDOutput(converter, args...)
+}
+
+// Convert - Remuxes or transcodes the video into new, returning the action that
+// was taken in the end. A failed remux, e.g. because the container does not
+// support one of the streams, is retried as a transcode. If new is the video
+// itself, it is replaced once the conversion succeeded.
+func Convert(decision Decision, old string, new string) (string, error) {
+	conf := config.GetConfig().Conversion
+
+	if !exec.IsCommandInPath(conf.Converter) {
+		return "", fmt.Errorf("Command \"%s\" is not in path, cannot convert", conf.Converter)
+	}
+
+	out := new
+	if out == old {
+		out = strings.TrimSuffix(old, filepath.Ext(old)) + ".converting" + filepath.Ext(old)
+	}
+
+	// Partial output is removed after failures, unless the file was there before
+	_, statErr := os.Stat(out)
+	cleanUp := func() {
+		if os.IsNotExist(statErr) {
+			os.Remove(out)
+		}
+	}
+
+	action := decision.Action
+
+	if action == ActionRemux && conf.RemuxTemplate != "" {
+		err := run(conf.RemuxTemplate, old, out)
+		if err == nil {
+			return action, finish(out, new)
+		}
+
+		logger.Warnf("Could not remux %s, transcoding it instead: %s", old, err.Error())
+		cleanUp()
+	}
+
+	action = ActionTranscode
+
+	if err := run(conf.ArgsTemplate, old, out); err != nil {
+		cleanUp()
+		return action, err
+	}
+
+	return action, finish(out, new)
+}
+
+// finish - Moves a conversion written next to the video over it
+func finish(out string, new string) error {
+	if out == new {
+		return nil
+	}
+
+	return os.Rename(out, new)
+}
@@ -0,0 +1,119 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+	"torrentRenamer/config"
+	"torrentRenamer/probe"
+)
+
+func setConversion(format string, probeVideos bool, videoCodecs []string, audioCodecs []string) {
+	conversion := &config.GetConfig().Conversion
+	conversion.Format = format
+	conversion.Probe = probeVideos
+	conversion.VideoCodecs = videoCodecs
+	conversion.AudioCodecs = audioCodecs
+}
+
+func probeResult(formatName string, videoCodec string, audioCodecs ...string) *probe.Result {
+	result := &probe.Result{
+		Format:  probe.Format{FormatName: formatName},
+		Streams: []probe.Stream{{CodecType: probe.TypeVideo, CodecName: videoCodec}},
+	}
+
+	for _, codec := range audioCodecs {
+		result.Streams = append(result.Streams, probe.Stream{CodecType: probe.TypeAudio, CodecName: codec})
+	}
+
+	return result
+}
+
+func TestDecideByProbe(t *testing.T) {
+	setConversion(".MKV", true, []string{"h264", "hevc"}, []string{"aac", "ac3", "dts"})
+
+	tests := []struct {
+		name   string
+		file   string
+		result *probe.Result
+		want   string
+	}{
+		{"in the format", "video.mkv", probeResult("matroska,webm", "h264", "aac"), ActionNone},
+		{"other container", "video.mp4", probeResult("mov,mp4,m4a,3gp,3g2,mj2", "h264", "aac"), ActionRemux},
+		{"mislabeled container", "video.mkv", probeResult("mov,mp4,m4a,3gp,3g2,mj2", "hevc", "ac3"), ActionRemux},
+		{"other extension", "video.webm", probeResult("matroska,webm", "h264", "aac"), ActionRemux},
+		{"video codec", "video.avi", probeResult("avi", "mpeg4", "aac"), ActionTranscode},
+		{"audio codec", "video.mkv", probeResult("matroska,webm", "h264", "aac", "mp3"), ActionTranscode},
+		{"codecs ignore case", "video.mkv", probeResult("matroska,webm", "H264", "DTS"), ActionNone},
+	}
+
+	for _, test := range tests {
+		if got := decideByProbe(test.file, test.result); got.Action != test.want {
+			t.Errorf("%s: decideByProbe(%s) = %s (%s), want %s", test.name, test.file, got.Action, got.Reason, test.want)
+		}
+	}
+
+	setConversion("mkv", true, nil, nil)
+
+	if got := decideByProbe("video.avi", probeResult("avi", "mpeg4", "mp3")); got.Action != ActionRemux {
+		t.Errorf("decideByProbe() without codec lists = %s, want %s", got.Action, ActionRemux)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	setConversion("mp4", false, []string{"h264"}, []string{"aac"})
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"video.mp4", ActionNone},
+		{"video.MP4", ActionNone},
+		{"video.mkv", ActionTranscode},
+		{"video.avi", ActionTranscode},
+	}
+
+	for _, test := range tests {
+		if got := Decide(test.file); got.Action != test.want {
+			t.Errorf("Decide(%s) without probing = %s, want %s", test.file, got.Action, test.want)
+		}
+	}
+
+	// A stand-in for ffprobe, which finds an h264/aac stream in matroska
+	available, video := probeAvailable, probeVideo
+	defer func() { probeAvailable, probeVideo = available, video }()
+
+	probeAvailable = func() bool { return true }
+	probeVideo = func(string) (*probe.Result, error) {
+		return probeResult("matroska,webm", "h264", "aac"), nil
+	}
+
+	setConversion("mp4", true, []string{"h264"}, []string{"aac"})
+
+	if got := Decide("video.mkv"); got.Action != ActionRemux {
+		t.Errorf("Decide(video.mkv) = %s (%s), want %s", got.Action, got.Reason, ActionRemux)
+	}
+
+	probeAvailable = func() bool { return false }
+
+	if got := Decide("video.mkv"); got.Action != ActionTranscode {
+		t.Errorf("Decide(video.mkv) without ffprobe = %s (%s), want %s", got.Action, got.Reason, ActionTranscode)
+	}
+}
+
+func TestGetArgs(t *testing.T) {
+	template := config.GetConfig().Conversion.ArgsTemplate
+
+	setConversion("mkv", true, []string{"hevc", "h264"}, []string{"aac"})
+
+	want := []string{"-i", "/videos/My Movie.avi", "-c:v", "hevc", "-c:a", "aac", "/videos/My Movie.mkv"}
+	if got, err := getArgs(template, "/videos/My Movie.avi", "/videos/My Movie.mkv"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("getArgs() = %q (%v), want %q", got, err, want)
+	}
+
+	setConversion("mkv", true, nil, nil)
+
+	want = []string{"-i", "/videos/My Movie.avi", "/videos/My Movie.mkv"}
+	if got, err := getArgs(template, "/videos/My Movie.avi", "/videos/My Movie.mkv"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("getArgs() without codec lists = %q (%v), want %q", got, err, want)
+	}
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/library"
+	"torrentRenamer/logger"
+	"torrentRenamer/notify"
+)
+
+const (
+	TypeParsed    = "parsed"
+	TypeLookup    = "lookup"
+	TypeMove      = "move"
+	TypeSkip      = "skip"
+	TypeDuplicate = "duplicate"
+	TypeReplace   = "replace"
+	TypeConvert   = "convert"
+	TypeError     = "error"
+	TypeSummary   = "summary"
+)
+
+// Event - Something that happened while processing a batch. In JSON output,
+// every event is written to stdout as one line. The message, if there is one,
+// is logged either way.
+type Event struct {
+	Type        string               `json:"type"`
+	Time        time.Time            `json:"time"`
+	Source      string               `json:"source,omitempty"`
+	Destination string               `json:"destination,omitempty"`
+	Service     string               `json:"service,omitempty"`
+	Video       torrentRenamer.Video `json:"video,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	Message     string               `json:"message,omitempty"`
+	Summary     *notify.Summary      `json:"summary,omitempty"`
+	Duplicates  []library.Item       `json:"duplicates,omitempty"`
+}
+
+var (
+	output io.Writer = os.Stdout
+	lock   sync.Mutex
+)
+
+// getLevel - Errors are logged as errors, skipped videos and duplicates as
+// warnings
+func getLevel(eventType string) logger.Level {
+	switch eventType {
+	case TypeError:
+		return logger.LevelError
+	case TypeSkip, TypeDuplicate:
+		return logger.LevelWarn
+	}
+
+	return logger.LevelInfo
+}
+
+// IsJSON - Returns whether events are written as JSON, in which case stdout is
+// reserved for them
+func IsJSON() bool {
+	return config.GetConfig().Output == "json"
+}
+
+// Emit - Logs the event's message and, in JSON output, writes the event to
+// stdout
+func Emit(event Event) {
+	if event.Message != "" {
+		logger.Log(getLevel(event.Type), event.Message)
+	}
+
+	if !IsJSON() {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		data, _ = json.Marshal(Event{Type: TypeError, Time: event.Time, Error: err.Error()})
+	}
+
+	output.Write(append(data, '\n'))
+}
+
+// Error - Emits an error event, printed as the message in text output
+func Error(source string, message string, err error) {
+	Emit(Event{Type: TypeError, Source: source, Error: err.Error(), Message: message})
+}
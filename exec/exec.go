@@ -1,12 +1,23 @@
 package exec
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"torrentRenamer/logger"
+)
+
+// CommandOutput - Where the output of commands is printed, stdout unless it is
+// reserved for machine-readable output
+var CommandOutput io.Writer = os.Stdout
+
+var (
+	commandsInPath    = make(map[string]bool)
+	commandsInPathMux sync.Mutex
 )
 
 func parseCommandArgInterfaces(args ...interface{}) ([]string, []string, error) {
@@ -30,19 +41,67 @@ func parseCommandArgInterfaces(args ...interface{}) ([]string, []string, error)
 }
 
 func Execute(args []string, inReader io.Reader, outWriter io.Writer, errWriter io.Writer) error {
+	return ExecuteContext(context.Background(), args, inReader, outWriter, errWriter)
+}
+
+// ExecuteContext - Functions just as exec.Execute, but kills the command, and
+// whatever it started, once the given context is done
+func ExecuteContext(ctx context.Context, args []string, inReader io.Reader, outWriter io.Writer, errWriter io.Writer) error {
 	cmd := exec.Command(args[0], args[1:]...)
 
 	cmd.Stdin = inReader
 	cmd.Stdout = outWriter
 	cmd.Stderr = errWriter
 
+	// Commands that cannot be cancelled stay in the terminal's process group,
+	// so they can read from it and get its interrupts
+	if ctx.Done() == nil {
+		return cmd.Run()
+	}
+
+	// Killing only the command would leave its children holding the output
+	// open, so it gets a process group of its own
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	err := cmd.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// ExecuteCommandWithEnv - Executes the given command with the variables added
+// to the environment, printing output to stdout and stderr.
+func ExecuteCommandWithEnv(env []string, cmdStr string, args ...string) error {
+	cmd := exec.Command(cmdStr, args...)
+
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = CommandOutput
+	cmd.Stderr = os.Stderr
+
 	return cmd.Run()
 }
 
 // ExecuteCommandWithSTDOutput - Executes the given command, printing output to
 // stdout and stderr.
 func ExecuteCommandWithSTDOutput(cmdStr string, args ...string) error {
-	return Execute(append([]string{cmdStr}, args...), os.Stdin, os.Stdout, os.Stderr)
+	return Execute(append([]string{cmdStr}, args...), os.Stdin, CommandOutput, os.Stderr)
 }
 
 // ExecuteCommand - Executes the given command, inserting output into a string
@@ -67,7 +126,7 @@ func ExecuteCommandWithLog(cmdStr string, args ...interface{}) (string, error) {
 		return "", err
 	}
 
-	fmt.Println("Executing command:", cmdStr, strings.Join(logArgs, " "))
+	logger.Infof("Executing command: %s %s", cmdStr, strings.Join(logArgs, " "))
 	return ExecuteCommand(cmdStr, executeArgs...)
 }
 
@@ -79,13 +138,22 @@ func ExecuteCommandWithSTDOutputAndLog(cmdStr string, args ...interface{}) error
 		return err
 	}
 
-	fmt.Println("Executing command:", cmdStr, strings.Join(logArgs, " "))
+	logger.Infof("Executing command: %s %s", cmdStr, strings.Join(logArgs, " "))
 	return ExecuteCommandWithSTDOutput(cmdStr, executeArgs...)
 }
 
-// IsCommandInPath - Returns true if command succeeds, false otherwise
-func IsCommandInPath(cmd string, args ...string) bool {
-	_, err := ExecuteCommand("/bin/sh", append([]string{"-c", fmt.Sprintf("command -v %s", cmd)}, args...)...)
+// IsCommandInPath - Returns true if the command is an executable in path, or
+// at the given path. Each command is only looked up once.
+func IsCommandInPath(cmd string) bool {
+	commandsInPathMux.Lock()
+	defer commandsInPathMux.Unlock()
+
+	found, ok := commandsInPath[cmd]
+	if !ok {
+		_, err := exec.LookPath(cmd)
+		found = err == nil
+		commandsInPath[cmd] = found
+	}
 
-	return err == nil
+	return found
 }
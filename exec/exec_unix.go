@@ -2,7 +2,21 @@
 
 package exec
 
+import (
+	"os/exec"
+	"syscall"
+)
+
 // ExecuteSudoCommand - Executes the given command with attempted elevated priveleges
 func ExecuteSudoCommand(cmdStr string, args ...string) (string, error) {
 	return ExecuteCommand("sudo", append([]string{cmdStr}, args...)...)
 }
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup - Kills the command and every process it started
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
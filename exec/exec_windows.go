@@ -1,6 +1,16 @@
 package exec
 
+import "os/exec"
+
 // ExecuteSudoCommand - Executes the given command with attempted elevated priveleges
 func ExecuteSudoCommand(cmdStr string, args ...string) (string, error) {
 	return ExecuteCommand(cmdStr, args...)
 }
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup - Kills the command. Windows has no process groups to kill
+// its children with.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
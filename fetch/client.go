@@ -0,0 +1,304 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"torrentRenamer/logger"
+)
+
+const (
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+)
+
+type ClientOptions struct {
+	Timeout           time.Duration
+	MaxRetries        int
+	RequestsPerSecond float64
+	HostRateLimits    map[string]float64
+	CABundle          string
+	Proxy             string
+	UserAgent         string
+	FixtureMode       string
+	FixtureDir        string
+	// Transport replaces the network transport when set, e.g. in tests
+	Transport http.RoundTripper
+}
+
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+type Client struct {
+	http       *http.Client
+	options    ClientOptions
+	limiters   map[string]*hostLimiter
+	limiterMux sync.Mutex
+	quotas     map[string]map[string]string
+	quotaMux   sync.RWMutex
+}
+
+// NewClient - Creates a client that limits the request rate per host and
+// retries rate limited or failed requests with exponential backoff.
+func NewClient(options ClientOptions) (*Client, error) {
+	transport, err := newTransport(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		http:     &http.Client{Timeout: options.Timeout, Transport: transport},
+		options:  options,
+		limiters: make(map[string]*hostLimiter),
+		quotas:   make(map[string]map[string]string),
+	}, nil
+}
+
+func newTransport(options *ClientOptions) (http.RoundTripper, error) {
+	if options.Transport != nil {
+		return NewTransport(options.FixtureMode, options.FixtureDir, options.Transport)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy URL %s: %s", options.Proxy, err.Error())
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if options.CABundle != "" {
+		pem, err := ioutil.ReadFile(options.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read CA bundle: %s", err.Error())
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA bundle %s", options.CABundle)
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return NewTransport(options.FixtureMode, options.FixtureDir, transport)
+}
+
+func (c *Client) getLimiter(host string) *hostLimiter {
+	c.limiterMux.Lock()
+	defer c.limiterMux.Unlock()
+
+	limiter, ok := c.limiters[host]
+	if !ok {
+		rate := c.options.RequestsPerSecond
+		if hostRate, ok := c.options.HostRateLimits[host]; ok {
+			rate = hostRate
+		}
+
+		limiter = newHostLimiter(rate)
+		c.limiters[host] = limiter
+	}
+
+	return limiter
+}
+
+func (c *Client) send(ctx context.Context, method string, requestURL string, header http.Header, body []byte) (*Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.options.UserAgent != "" {
+		req.Header.Set("User-Agent", c.options.UserAgent)
+	}
+
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	if err = c.getLimiter(req.URL.Host).wait(ctx); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("%s %s", method, redactURL(req.URL))
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       resBody,
+	}
+
+	c.recordQuota(req.URL.Host, res.Header)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return ret, &StatusError{
+			Method:     method,
+			URL:        redactURL(req.URL),
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Body:       resBody,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+
+	return ret, nil
+}
+
+// Do - Sends the request, waiting for the host's rate limit. GET and HEAD
+// requests are retried after 429 and 5xx responses, timeouts and temporary
+// network errors until MaxRetries is reached. Other requests are sent once, as
+// the server may have acted on them already.
+func (c *Client) Do(ctx context.Context, method string, requestURL string, header http.Header, body []byte) (*Response, error) {
+	var res *Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		res, err = c.send(ctx, method, requestURL, header, body)
+		if err == nil || attempt >= c.options.MaxRetries || !isRetryable(ctx, method, err) {
+			return res, err
+		}
+
+		delay := backoff(attempt)
+		reason := "network error"
+
+		if statusErr, ok := err.(*StatusError); ok {
+			reason = statusErr.Status
+
+			if statusErr.RetryAfter > delay {
+				delay = statusErr.RetryAfter
+			}
+		}
+
+		if parsed, parseErr := url.Parse(requestURL); parseErr == nil {
+			logger.Debugf("Retrying %s %s in %s after %s", method, redactURL(parsed), delay.String(), reason)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// recordQuota - Remembers the rate limit and quota headers of the latest
+// response from the host
+func (c *Client) recordQuota(host string, header http.Header) {
+	quota := make(map[string]string)
+
+	for key := range header {
+		lowKey := strings.ToLower(key)
+
+		if strings.Contains(lowKey, "ratelimit") || strings.Contains(lowKey, "quota") || lowKey == "retry-after" {
+			quota[key] = header.Get(key)
+		}
+	}
+
+	if len(quota) == 0 {
+		return
+	}
+
+	c.quotaMux.Lock()
+	c.quotas[host] = quota
+	c.quotaMux.Unlock()
+}
+
+// GetQuota - Returns the rate limit and quota headers of the latest response
+// from the host, if it sent any
+func (c *Client) GetQuota(host string) map[string]string {
+	c.quotaMux.RLock()
+	defer c.quotaMux.RUnlock()
+
+	return c.quotas[host]
+}
+
+func isRetryable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil || (method != http.MethodGet && method != http.MethodHead) {
+		return false
+	}
+
+	if statusErr, ok := err.(*StatusError); ok {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && (netErr.Timeout() || netErr.Temporary())
+}
+
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff << uint(attempt)
+	if delay <= 0 || delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	// Jitter keeps the goroutines of one batch from retrying in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter - Returns how long the Retry-After header, either seconds or
+// a date, asks to wait. Invalid values and dates in the past ask for nothing.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var ret time.Duration
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		ret = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		ret = time.Until(date)
+	}
+
+	if ret < 0 {
+		return 0
+	}
+
+	return ret
+}
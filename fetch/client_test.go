@@ -0,0 +1,70 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{value: "", min: 0, max: 0},
+		{value: "0", min: 0, max: 0},
+		{value: "120", min: 2 * time.Minute, max: 2 * time.Minute},
+		{value: " 5 ", min: 5 * time.Second, max: 5 * time.Second},
+		{value: "-5", min: 0, max: 0},
+		{value: "soon", min: 0, max: 0},
+		{value: now.Add(90 * time.Second).UTC().Format(http.TimeFormat), min: 80 * time.Second, max: 90 * time.Second},
+		{value: now.Add(-time.Hour).UTC().Format(http.TimeFormat), min: 0, max: 0},
+	}
+
+	for _, test := range tests {
+		got := parseRetryAfter(test.value)
+		if got < test.min || got > test.max {
+			t.Errorf("parseRetryAfter(%q) = %s, want between %s and %s", test.value, got, test.min, test.max)
+		}
+	}
+}
+
+// timeoutError - A net.Error, as returned by dials and reads that time out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		err    error
+		want   bool
+	}{
+		{"too many requests", context.Background(), http.MethodGet, &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", context.Background(), http.MethodHead, &StatusError{StatusCode: http.StatusBadGateway}, true},
+		{"client error", context.Background(), http.MethodGet, &StatusError{StatusCode: http.StatusNotFound}, false},
+		{"timeout", context.Background(), http.MethodGet, &net.OpError{Op: "dial", Err: timeoutError{}}, true},
+		{"other error", context.Background(), http.MethodGet, errors.New("x509: certificate signed by unknown authority"), false},
+		{"post", context.Background(), http.MethodPost, &StatusError{StatusCode: http.StatusServiceUnavailable}, false},
+		{"canceled", canceled, http.MethodGet, &StatusError{StatusCode: http.StatusServiceUnavailable}, false},
+	}
+
+	for _, test := range tests {
+		if got := isRetryable(test.ctx, test.method, test.err); got != test.want {
+			t.Errorf("%s: isRetryable() = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package fetch
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+var redactedParams = []string{"apikey", "api_key", "token", "X-Plex-Token"}
+
+// redactedPaths - The path prefixes of webhook URLs, by host, after which every
+// path segment is a secret, e.g. the ID and token of a Discord webhook
+var redactedPaths = map[string]string{
+	"discord.com":     "/api/webhooks/",
+	"discordapp.com":  "/api/webhooks/",
+	"hooks.slack.com": "/services/",
+}
+
+var redactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Emby-Token",
+	"X-Gotify-Key",
+	"X-Plex-Token",
+}
+
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Body       []byte
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s returned %s", e.Method, e.URL, e.Status)
+}
+
+// IsStatus - Returns true if err is a StatusError with the given status code
+func IsStatus(err error, statusCode int) bool {
+	statusErr, ok := err.(*StatusError)
+
+	return ok && statusErr.StatusCode == statusCode
+}
+
+// redactPath - Replaces the secret segments of webhook paths
+func redactPath(host string, path string) string {
+	host = strings.ToLower(host)
+
+	for secretHost, prefix := range redactedPaths {
+		if host != secretHost && !strings.HasSuffix(host, "."+secretHost) {
+			continue
+		}
+
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		segments := strings.Split(strings.TrimPrefix(path, prefix), "/")
+		for i, segment := range segments {
+			if segment != "" {
+				segments[i] = redacted
+			}
+		}
+
+		return prefix + strings.Join(segments, "/")
+	}
+
+	return path
+}
+
+// redactURL - Removes credentials, secret query parameters and the secret path
+// segments of webhooks from the URL
+func redactURL(u *url.URL) string {
+	ret := *u
+	query := ret.Query()
+
+	for _, param := range redactedParams {
+		if query.Get(param) != "" {
+			query.Set(param, redacted)
+		}
+	}
+
+	if ret.User != nil {
+		ret.User = url.User(redacted)
+	}
+
+	ret.Path = redactPath(ret.Hostname(), ret.Path)
+	ret.RawPath = ""
+	ret.RawQuery = query.Encode()
+
+	return ret.String()
+}
+
+// redactHeader - Returns a copy of the header without credentials and tokens
+func redactHeader(header http.Header) http.Header {
+	ret := http.Header{}
+
+	for key, values := range header {
+		ret[key] = values
+	}
+
+	for _, key := range redactedHeaders {
+		if ret.Get(key) != "" {
+			ret.Set(key, redacted)
+		}
+	}
+
+	return ret
+}
@@ -1,19 +1,147 @@
 package fetch
 
 import (
-	"io/ioutil"
+	"context"
+	"encoding/json"
 	"net/http"
+	"sync"
+	"time"
+	"torrentRenamer/config"
 )
 
+const (
+	defaultTimeout           = 15 * time.Second
+	defaultRequestsPerSecond = 5
+)
+
+var (
+	defaultClient *Client
+	clientErr     error
+	clientOnce    sync.Once
+)
+
+// GetClient - Returns the client shared by every service, configured from the
+// network section of the config the first time it is requested.
+func GetClient() (*Client, error) {
+	clientOnce.Do(func() {
+		network := config.GetConfig().Network
+
+		timeout := time.Duration(network.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+
+		rate := network.RequestsPerSecond
+		if rate == 0 {
+			rate = defaultRequestsPerSecond
+		}
+
+		defaultClient, clientErr = NewClient(ClientOptions{
+			Timeout:           timeout,
+			MaxRetries:        network.MaxRetries,
+			RequestsPerSecond: rate,
+			HostRateLimits:    network.HostRateLimits,
+			CABundle:          network.CaBundle,
+			Proxy:             network.Proxy,
+			UserAgent:         network.UserAgent,
+			FixtureMode:       network.FixtureMode,
+			FixtureDir:        network.FixtureDir,
+		})
+	})
+
+	return defaultClient, clientErr
+}
+
+// SetClient - Replaces the shared client, e.g. with one replaying fixtures in
+// tests.
+func SetClient(client *Client) {
+	clientOnce.Do(func() {})
+	defaultClient, clientErr = client, nil
+}
+
+// Get - Fetches the given URL with the shared client and returns the body.
 func Get(url string) ([]byte, error) {
-	var response []byte
+	return GetContext(context.Background(), url)
+}
+
+// GetContext - Functions just as fetch.Get, but stops waiting or retrying once
+// the given context is done.
+func GetContext(ctx context.Context, url string) ([]byte, error) {
+	client, err := GetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// GetWithHeader - Functions just as fetch.Get, but sends the given headers
+// along, e.g. to authenticate.
+func GetWithHeader(url string, header http.Header) ([]byte, error) {
+	return GetWithHeaderContext(context.Background(), url, header)
+}
 
-	req, err := http.Get(url)
+// GetWithHeaderContext - Functions just as fetch.GetWithHeader, but stops
+// waiting or retrying once the given context is done.
+func GetWithHeaderContext(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	client, err := GetClient()
 	if err != nil {
-		return response, err
+		return nil, err
+	}
+
+	res, err := client.Do(ctx, http.MethodGet, url, header, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// Post - Posts the body to the given URL with the shared client and returns
+// the body of the response.
+func Post(url string, header http.Header, body []byte) ([]byte, error) {
+	return PostContext(context.Background(), url, header, body)
+}
+
+// PostContext - Functions just as fetch.Post, but stops waiting once the given
+// context is done.
+func PostContext(ctx context.Context, url string, header http.Header, body []byte) ([]byte, error) {
+	client, err := GetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(ctx, http.MethodPost, url, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// PostJSON - Functions just as fetch.Post, but encodes the payload as JSON.
+func PostJSON(url string, header http.Header, payload interface{}) ([]byte, error) {
+	return PostJSONContext(context.Background(), url, header, payload)
+}
+
+// PostJSONContext - Functions just as fetch.PostJSON, but stops waiting once
+// the given context is done.
+func PostJSONContext(ctx context.Context, url string, header http.Header, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if header == nil {
+		header = http.Header{}
 	}
 
-	defer req.Body.Close()
+	header.Set("Content-Type", "application/json")
 
-	return ioutil.ReadAll(req.Body)
+	return PostContext(ctx, url, header, body)
 }
@@ -0,0 +1,53 @@
+package fetch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type hostLimiter struct {
+	interval time.Duration
+	next     time.Time
+	lock     sync.Mutex
+}
+
+func newHostLimiter(requestsPerSecond float64) *hostLimiter {
+	var interval time.Duration
+	if requestsPerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+
+	return &hostLimiter{interval: interval}
+}
+
+// wait - Reserves the next free slot for the host and blocks until it arrives
+func (l *hostLimiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.lock.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.lock.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
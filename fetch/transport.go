@@ -0,0 +1,187 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ModeLive   = "live"
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+type fixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type fixtureResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+type fixture struct {
+	Request  fixtureRequest  `json:"request"`
+	Response fixtureResponse `json:"response"`
+}
+
+// recordTransport - Passes requests on to the network and saves every
+// request/response pair as a fixture file.
+type recordTransport struct {
+	next http.RoundTripper
+	dir  string
+}
+
+// replayTransport - Answers requests from the fixture files saved by
+// recordTransport without touching the network.
+type replayTransport struct {
+	dir string
+}
+
+type FixtureNotFoundError struct {
+	Method string
+	URL    string
+	Path   string
+}
+
+func (e *FixtureNotFoundError) Error() string {
+	return fmt.Sprintf("No fixture for %s %s (expected %s)", e.Method, e.URL, e.Path)
+}
+
+// NewTransport - Wraps the live transport according to the fixture mode, which
+// is one of ModeLive, ModeRecord or ModeReplay.
+func NewTransport(mode string, fixtureDir string, live http.RoundTripper) (http.RoundTripper, error) {
+	switch mode {
+	case "", ModeLive:
+		return live, nil
+	case ModeRecord:
+		if err := os.MkdirAll(fixtureDir, 0755); err != nil {
+			return nil, err
+		}
+
+		return &recordTransport{next: live, dir: fixtureDir}, nil
+	case ModeReplay:
+		return &replayTransport{dir: fixtureDir}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown fetch mode %s, expected %s, %s or %s", mode, ModeLive, ModeRecord, ModeReplay)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// fixturePath - Names fixtures after the request with secrets removed, so the
+// same fixtures can be replayed with any API key or webhook token
+func fixturePath(dir string, req *http.Request, body []byte) (string, fixtureRequest) {
+	request := fixtureRequest{
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+		Body:   string(body),
+	}
+
+	hash := sha256.Sum256([]byte(request.Method + " " + request.URL + "\n" + request.Body))
+	name := fmt.Sprintf("%s_%s_%s.json", strings.Replace(req.URL.Host, ":", "_", -1), strings.ToLower(req.Method), hex.EncodeToString(hash[:])[:16])
+
+	return filepath.Join(dir, name), request
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	path, request := fixturePath(t.dir, req, body)
+	saved := fixture{
+		Request: request,
+		Response: fixtureResponse{
+			StatusCode: res.StatusCode,
+			Header:     redactHeader(res.Header),
+			Body:       string(resBody),
+		},
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "\t")
+
+	err = encoder.Encode(saved)
+	if err == nil {
+		err = ioutil.WriteFile(path, data.Bytes(), 0644)
+	}
+
+	return res, err
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	path, request := fixturePath(t.dir, req, body)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &FixtureNotFoundError{Method: request.Method, URL: request.URL, Path: path}
+		}
+
+		return nil, err
+	}
+
+	var saved fixture
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("Invalid fixture %s: %s", path, err.Error())
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", saved.Response.StatusCode, http.StatusText(saved.Response.StatusCode)),
+		StatusCode:    saved.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        saved.Response.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(saved.Response.Body)),
+		ContentLength: int64(len(saved.Response.Body)),
+		Request:       req,
+	}, nil
+}
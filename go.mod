@@ -1,6 +1,6 @@
 module torrentRenamer
 
-go 1.12
+go 1.13
 
 require (
 	github.com/middelink/go-parse-torrent-name v0.0.0-20190301154245-3ff4efacd4c4
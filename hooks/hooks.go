@@ -0,0 +1,116 @@
+package hooks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/exec"
+	"torrentRenamer/logger"
+	"torrentRenamer/util"
+)
+
+const (
+	OnParsed        = "onParsed"
+	OnMoved         = "onMoved"
+	OnConverted     = "onConverted"
+	OnFailed        = "onFailed"
+	OnBatchComplete = "onBatchComplete"
+)
+
+// Data - What the arguments of hooks are rendered from. Video is nil for
+// onBatchComplete, the lists are only set for it.
+type Data struct {
+	Event     string
+	Video     torrentRenamer.Video
+	Old       string
+	New       string
+	Error     string
+	Moved     []string
+	NotMoved  []string
+	Failed    []string
+	Converted []string
+}
+
+// getEnv - Returns the variables passed to hook commands, so scripts do not
+// need arguments for everything
+func getEnv(data *Data) []string {
+	env := map[string]string{
+		"TR_EVENT":    data.Event,
+		"TR_OLD_PATH": data.Old,
+		"TR_NEW_PATH": data.New,
+		"TR_ERROR":    data.Error,
+	}
+
+	switch v := data.Video.(type) {
+	case *torrentRenamer.Movie:
+		env["TR_TYPE"] = "movie"
+		env["TR_NAME"] = v.Name
+		env["TR_YEAR"] = strconv.Itoa(v.Year)
+		env["TR_IMDB_ID"] = v.ImdbID
+	case *torrentRenamer.Show:
+		env["TR_TYPE"] = "show"
+		env["TR_NAME"] = v.Name
+		env["TR_SEASON"] = strconv.Itoa(v.Season)
+		env["TR_EPISODE"] = strconv.Itoa(v.Episode)
+		env["TR_TITLE"] = v.Title
+		env["TR_IMDB_ID"] = v.ImdbID
+	}
+
+	if data.Event == OnBatchComplete {
+		env["TR_MOVED"] = strings.Join(data.Moved, "\n")
+		env["TR_NOT_MOVED"] = strings.Join(data.NotMoved, "\n")
+		env["TR_FAILED"] = strings.Join(data.Failed, "\n")
+		env["TR_CONVERTED"] = strings.Join(data.Converted, "\n")
+	}
+
+	ret := make([]string, 0, len(env))
+	for key, value := range env {
+		if value != "" {
+			ret = append(ret, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	return ret
+}
+
+// Run - Runs the commands configured for the event, with every argument
+// rendered as a template from the data. Returns an error describing every
+// command that failed.
+func Run(event string, data Data) error {
+	commands := config.GetConfig().Hooks[event]
+	if len(commands) == 0 {
+		return nil
+	}
+
+	data.Event = event
+	env := getEnv(&data)
+	failed := make([]string, 0)
+
+	for _, command := range commands {
+		args := make([]string, len(command.Args))
+
+		var err error
+		for i, arg := range command.Args {
+			if args[i], err = util.InsertTemplateData(arg, data); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			logger.Debugf("Running %s hook: %s %s", event, command.Command, strings.Join(args, " "))
+			err = exec.ExecuteCommandWithEnv(env, command.Command, args...)
+		}
+
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", command.Command, err.Error()))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%s hook(s) failed: %s", event, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
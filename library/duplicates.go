@@ -0,0 +1,142 @@
+package library
+
+import (
+	"fmt"
+	"torrentRenamer/match"
+)
+
+// SameVideo - Returns true if both items are the same movie or episode, going
+// by their IMDb IDs when both have one and by their names otherwise
+func SameVideo(a Item, b Item) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	if a.Type == TypeShow {
+		if a.Season != b.Season || a.Episode != b.Episode {
+			return false
+		}
+
+		if a.SeriesImdbID != "" && b.SeriesImdbID != "" {
+			return a.SeriesImdbID == b.SeriesImdbID
+		}
+
+		return match.Normalize(a.Name) == match.Normalize(b.Name)
+	}
+
+	if a.ImdbID != "" && b.ImdbID != "" {
+		return a.ImdbID == b.ImdbID
+	}
+
+	if a.Year != 0 && b.Year != 0 && a.Year != b.Year {
+		return false
+	}
+
+	return match.Normalize(a.Name) == match.Normalize(b.Name)
+}
+
+// SameContent - Returns true if both files are known to have the same content,
+// which takes checksums of both
+func SameContent(a Item, b Item) bool {
+	return a.Checksum != "" && a.Checksum == b.Checksum
+}
+
+// FindDuplicates - Returns the items at other paths that are the same video as
+// the item, or have the same content
+func (idx *Index) FindDuplicates(item Item) []Item {
+	ret := make([]Item, 0)
+
+	for _, other := range idx.All() {
+		if other.Path == item.Path {
+			continue
+		}
+
+		if SameVideo(item, other) || SameContent(item, other) {
+			ret = append(ret, other)
+		}
+	}
+
+	return ret
+}
+
+// bucketKey - Only items in the same bucket can be the same video, which keeps
+// comparing every item with every other one cheap for episodes
+func bucketKey(item Item) string {
+	if item.Type == TypeShow {
+		return fmt.Sprintf("%s:%d:%d", item.Type, item.Season, item.Episode)
+	}
+
+	return item.Type
+}
+
+// Duplicates - Returns the groups of items that are the same video, and of
+// items with the same content
+func (idx *Index) Duplicates() [][]Item {
+	items := idx.All()
+
+	// Every item starts in its own group, which are merged as duplicates are
+	// found
+	groups := make([]int, len(items))
+	for i := range groups {
+		groups[i] = i
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if groups[i] != i {
+			groups[i] = find(groups[i])
+		}
+
+		return groups[i]
+	}
+
+	merge := func(a int, b int) {
+		groups[find(b)] = find(a)
+	}
+
+	buckets := make(map[string][]int)
+	checksums := make(map[string]int)
+
+	for i, item := range items {
+		key := bucketKey(item)
+
+		for _, j := range buckets[key] {
+			if SameVideo(items[j], item) {
+				merge(j, i)
+			}
+		}
+
+		buckets[key] = append(buckets[key], i)
+
+		if item.Checksum != "" {
+			if j, ok := checksums[item.Checksum]; ok {
+				merge(j, i)
+			} else {
+				checksums[item.Checksum] = i
+			}
+		}
+	}
+
+	members := make(map[int][]Item)
+	order := make([]int, 0)
+
+	for i, item := range items {
+		group := find(i)
+
+		if _, ok := members[group]; !ok {
+			order = append(order, group)
+		}
+
+		members[group] = append(members[group], item)
+	}
+
+	ret := make([][]Item, 0)
+
+	for _, group := range order {
+		if len(members[group]) > 1 {
+			ret = append(ret, members[group])
+		}
+	}
+
+	return ret
+}
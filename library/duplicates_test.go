@@ -0,0 +1,112 @@
+package library
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSameVideo(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Item
+		b    Item
+		want bool
+	}{
+		{
+			name: "same movie by name",
+			a:    Item{Type: TypeMovie, Name: "The Matrix", Year: 1999},
+			b:    Item{Type: TypeMovie, Name: "Matrix", Year: 1999},
+			want: true,
+		},
+		{
+			name: "movie without a year",
+			a:    Item{Type: TypeMovie, Name: "Amélie", Year: 2001},
+			b:    Item{Type: TypeMovie, Name: "Amelie"},
+			want: true,
+		},
+		{
+			name: "remake",
+			a:    Item{Type: TypeMovie, Name: "Dune", Year: 1984},
+			b:    Item{Type: TypeMovie, Name: "Dune", Year: 2021},
+			want: false,
+		},
+		{
+			name: "IMDb IDs win over names",
+			a:    Item{Type: TypeMovie, Name: "Dune", ImdbID: "tt0087182"},
+			b:    Item{Type: TypeMovie, Name: "Dune", ImdbID: "tt1160419"},
+			want: false,
+		},
+		{
+			name: "same episode",
+			a:    Item{Type: TypeShow, Name: "The Office", Season: 1, Episode: 2},
+			b:    Item{Type: TypeShow, Name: "the office", Season: 1, Episode: 2},
+			want: true,
+		},
+		{
+			name: "other episode",
+			a:    Item{Type: TypeShow, Name: "The Office", Season: 1, Episode: 2},
+			b:    Item{Type: TypeShow, Name: "The Office", Season: 1, Episode: 3},
+			want: false,
+		},
+		{
+			name: "series IDs win over names",
+			a:    Item{Type: TypeShow, Name: "The Office", Season: 1, Episode: 2, SeriesImdbID: "tt0386676"},
+			b:    Item{Type: TypeShow, Name: "The Office", Season: 1, Episode: 2, SeriesImdbID: "tt0290978"},
+			want: false,
+		},
+		{
+			name: "movie and episode",
+			a:    Item{Type: TypeMovie, Name: "The Office"},
+			b:    Item{Type: TypeShow, Name: "The Office", Season: 1, Episode: 1},
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		if got := SameVideo(test.a, test.b); got != test.want {
+			t.Errorf("%s: SameVideo() = %t, want %t", test.name, got, test.want)
+		}
+
+		if got := SameVideo(test.b, test.a); got != test.want {
+			t.Errorf("%s: SameVideo() reversed = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	idx := &Index{Items: []Item{
+		{Path: "/movies/The Matrix (1999).mkv", Type: TypeMovie, Name: "The Matrix", Year: 1999},
+		{Path: "/movies/Heat (1995).mkv", Type: TypeMovie, Name: "Heat", Year: 1995, Checksum: "abc"},
+		{Path: "/shows/The Office - S01E02.mkv", Type: TypeShow, Name: "The Office", Season: 1, Episode: 2},
+		{Path: "/downloads/Matrix.1999.avi", Type: TypeMovie, Name: "Matrix", Year: 1999},
+		{Path: "/downloads/heat-copy.mkv", Type: TypeMovie, Name: "Unknown", Checksum: "abc"},
+		{Path: "/shows/The Office - S01E03.mkv", Type: TypeShow, Name: "The Office", Season: 1, Episode: 3},
+		{Path: "/archive/The Office - S01E02.avi", Type: TypeShow, Name: "Office", Season: 1, Episode: 2},
+	}}
+
+	// Groups and their items are in the order of Index.All
+	want := [][]string{
+		{"/movies/Heat (1995).mkv", "/downloads/heat-copy.mkv"},
+		{"/downloads/Matrix.1999.avi", "/movies/The Matrix (1999).mkv"},
+		{"/archive/The Office - S01E02.avi", "/shows/The Office - S01E02.mkv"},
+	}
+
+	got := make([][]string, 0)
+	for _, group := range idx.Duplicates() {
+		paths := make([]string, len(group))
+		for i, item := range group {
+			paths[i] = item.Path
+		}
+
+		got = append(got, paths)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Duplicates() = %v, want %v", got, want)
+	}
+
+	duplicates := idx.FindDuplicates(Item{Path: "/new/Heat.mkv", Type: TypeMovie, Name: "Heat", Year: 1995})
+	if len(duplicates) != 1 || duplicates[0].Path != "/movies/Heat (1995).mkv" {
+		t.Errorf("FindDuplicates() = %v, want only /movies/Heat (1995).mkv", duplicates)
+	}
+}
@@ -0,0 +1,331 @@
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"torrentRenamer"
+	"torrentRenamer/config"
+)
+
+const (
+	indexVersion = 1
+
+	TypeMovie = "movie"
+	TypeShow  = "show"
+)
+
+// Item - A video torrentRenamer placed in the library
+type Item struct {
+	Path         string                 `json:"path"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	Year         int                    `json:"year,omitempty"`
+	Season       int                    `json:"season,omitempty"`
+	Episode      int                    `json:"episode,omitempty"`
+	Title        string                 `json:"title,omitempty"`
+	ImdbID       string                 `json:"imdbId,omitempty"`
+	SeriesImdbID string                 `json:"seriesImdbId,omitempty"`
+	TmdbID       string                 `json:"tmdbId,omitempty"`
+	Release      torrentRenamer.Release `json:"release"`
+	Size         int64                  `json:"size"`
+	Checksum     string                 `json:"checksum,omitempty"`
+	Added        time.Time              `json:"added"`
+}
+
+// String - Returns the name of the item, e.g. "The Matrix (1999)" or
+// "The Office S01E02 - Diversity Day"
+func (i Item) String() string {
+	if i.Type == TypeShow {
+		name := fmt.Sprintf("%s S%02dE%02d", i.Name, i.Season, i.Episode)
+		if i.Title != "" {
+			name += " - " + i.Title
+		}
+
+		return name
+	}
+
+	if i.Year == 0 {
+		return i.Name
+	}
+
+	return fmt.Sprintf("%s (%d)", i.Name, i.Year)
+}
+
+// Refresh - Updates the size of the item, and its checksum if it has one, e.g.
+// after tags were written into it
+func (i *Item) Refresh() error {
+	info, err := os.Stat(i.Path)
+	if err != nil {
+		return err
+	}
+
+	i.Size = info.Size()
+
+	if i.Checksum != "" {
+		if i.Checksum, err = Checksum(i.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Index - The items in the library, stored as JSON
+type Index struct {
+	Version int    `json:"version"`
+	Items   []Item `json:"items"`
+	path    string
+	lock    sync.Mutex
+}
+
+// Load - Reads the index at indexPath, an index that does not exist yet is
+// empty
+func Load(indexPath string) (*Index, error) {
+	idx := &Index{Version: indexVersion, Items: make([]Item, 0), path: indexPath}
+
+	data, err := ioutil.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("Could not read library index %s: %s", indexPath, err.Error())
+	}
+
+	if idx.Version != indexVersion {
+		return nil, fmt.Errorf("Library index %s has unknown version %d", indexPath, idx.Version)
+	}
+
+	return idx, nil
+}
+
+// Open - Loads the index configured with --library-index
+func Open() (*Index, error) {
+	indexPath := config.GetConfig().Library.IndexPath
+	if indexPath == "" {
+		return nil, errors.New("No library index is configured, set one with --library-index")
+	}
+
+	return Load(indexPath)
+}
+
+// Save - Writes the index, replacing the old one only once it was written
+// completely
+func (idx *Index) Save() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(idx, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := idx.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, idx.path)
+}
+
+func (idx *Index) find(itemPath string) int {
+	for i, item := range idx.Items {
+		if item.Path == itemPath {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Add - Adds the item, replacing the one with the same path
+func (idx *Index) Add(item Item) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if i := idx.find(item.Path); i >= 0 {
+		idx.Items[i] = item
+		return
+	}
+
+	idx.Items = append(idx.Items, item)
+}
+
+// Get - Returns the item at itemPath
+func (idx *Index) Get(itemPath string) (Item, bool) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if i := idx.find(itemPath); i >= 0 {
+		return idx.Items[i], true
+	}
+
+	return Item{}, false
+}
+
+// Remove - Removes the item at itemPath, returning false if there is none
+func (idx *Index) Remove(itemPath string) bool {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	i := idx.find(itemPath)
+	if i < 0 {
+		return false
+	}
+
+	idx.Items = append(idx.Items[:i], idx.Items[i+1:]...)
+
+	return true
+}
+
+// Move - Points the item at oldPath to newPath, e.g. after it was converted
+func (idx *Index) Move(oldPath string, newPath string) error {
+	item, ok := idx.Get(oldPath)
+	if !ok {
+		return nil
+	}
+
+	item.Path = newPath
+	if err := item.Refresh(); err != nil {
+		return err
+	}
+
+	idx.Remove(oldPath)
+	idx.Add(item)
+
+	return nil
+}
+
+// All - Returns the items sorted by name, season and episode
+func (idx *Index) All() []Item {
+	idx.lock.Lock()
+	items := append([]Item{}, idx.Items...)
+	idx.lock.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+
+		if !strings.EqualFold(a.Name, b.Name) {
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+
+		if a.Year != b.Year {
+			return a.Year < b.Year
+		}
+
+		if a.Season != b.Season {
+			return a.Season < b.Season
+		}
+
+		if a.Episode != b.Episode {
+			return a.Episode < b.Episode
+		}
+
+		return a.Path < b.Path
+	})
+
+	return items
+}
+
+// Search - Returns the items whose name, title, IDs or path contain all words
+// of the query
+func (idx *Index) Search(query string) []Item {
+	words := strings.Fields(strings.ToLower(query))
+	ret := make([]Item, 0)
+
+	for _, item := range idx.All() {
+		text := strings.ToLower(strings.Join([]string{item.String(), item.ImdbID, item.SeriesImdbID, item.TmdbID, item.Path}, " "))
+
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			ret = append(ret, item)
+		}
+	}
+
+	return ret
+}
+
+// Checksum - Returns the SHA-256 of the file's content
+func Checksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// NewItem - Describes the video at videoPath, with a checksum if checksums are
+// enabled
+func NewItem(videoPath string, video torrentRenamer.Video) (Item, error) {
+	info, err := os.Stat(videoPath)
+	if err != nil {
+		return Item{}, err
+	}
+
+	metadata := video.GetMetadata()
+
+	item := Item{
+		Path:    videoPath,
+		Name:    video.GetName(),
+		ImdbID:  metadata.ImdbID,
+		TmdbID:  metadata.TmdbID,
+		Release: *video.GetRelease(),
+		Size:    info.Size(),
+		Added:   time.Now().UTC(),
+	}
+
+	switch v := video.(type) {
+	case *torrentRenamer.Movie:
+		item.Type = TypeMovie
+		item.Year = v.Year
+	case *torrentRenamer.Show:
+		item.Type = TypeShow
+		item.Season = v.Season
+		item.Episode = v.Episode
+		item.Title = v.Title
+		item.SeriesImdbID = v.SeriesImdbID
+	}
+
+	if config.GetConfig().Library.Checksums {
+		if item.Checksum, err = Checksum(videoPath); err != nil {
+			return item, err
+		}
+	}
+
+	return item, nil
+}
@@ -0,0 +1,134 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"torrentRenamer/config"
+	"torrentRenamer/util"
+)
+
+// normalizeQuality - Makes e.g. "WEB-DL" and "webdl" or "x.264" and "x264"
+// equal
+func normalizeQuality(value string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return -1
+	}, strings.ToLower(value))
+}
+
+// rank - Returns the position of the value in the preferences, best first, or
+// -1 if it is unknown or not listed
+func rank(value string, preferences []string) int {
+	normalized := normalizeQuality(value)
+	if normalized == "" {
+		return -1
+	}
+
+	for i, preference := range preferences {
+		if normalizeQuality(preference) == normalized {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// compareRanks - Criteria that are unknown for either item cannot decide
+// anything, as renamed files often lost them
+func compareRanks(a string, b string, preferences []string) int {
+	rankA, rankB := rank(a, preferences), rank(b, preferences)
+	if rankA < 0 || rankB < 0 {
+		return 0
+	}
+
+	return rankB - rankA
+}
+
+// Compare - Returns a positive number if a is a better copy than b according to
+// the preferred resolutions, sources, codecs, PROPER/REPACK status and size, a
+// negative number if it is worse and 0 if they are equally good
+func Compare(a Item, b Item) int {
+	profile := config.GetConfig().Upgrades
+
+	for _, result := range []int{
+		compareRanks(a.Release.Resolution, b.Release.Resolution, profile.Resolutions),
+		compareRanks(a.Release.Source, b.Release.Source, profile.Sources),
+		compareRanks(a.Release.Codec, b.Release.Codec, profile.Codecs),
+	} {
+		if result != 0 {
+			return result
+		}
+	}
+
+	if profile.PreferProper {
+		properA := a.Release.Proper || a.Release.Repack
+		properB := b.Release.Proper || b.Release.Repack
+
+		if properA != properB {
+			if properA {
+				return 1
+			}
+
+			return -1
+		}
+	}
+
+	if a.Size == b.Size || profile.Size == "" {
+		return 0
+	}
+
+	if (a.Size > b.Size) == (profile.Size == "larger") {
+		return 1
+	}
+
+	return -1
+}
+
+// getArchivePath - Keeps the path below the movies or shows directory, under a
+// folder named like that directory, so archived videos do not collide with each
+// other
+func getArchivePath(itemPath string) string {
+	conf := config.GetConfig()
+
+	for _, dir := range []string{conf.DefaultDirectories.Movies, conf.DefaultDirectories.Shows} {
+		dir = filepath.Clean(dir)
+
+		rel, err := filepath.Rel(dir, itemPath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.Join(conf.Upgrades.ArchiveDir, filepath.Base(dir), rel)
+		}
+	}
+
+	return filepath.Join(conf.Upgrades.ArchiveDir, filepath.Base(itemPath))
+}
+
+// Archive - Moves the item's file into the archive directory, numbering it if
+// an older copy was archived there already, and returns where it was moved
+func Archive(item Item) (string, error) {
+	if config.GetConfig().Upgrades.ArchiveDir == "" {
+		return "", fmt.Errorf("No archive directory is configured, cannot replace %s", item.Path)
+	}
+
+	archivePath := getArchivePath(item.Path)
+	ext := filepath.Ext(archivePath)
+	base := strings.TrimSuffix(archivePath, ext)
+
+	for i := 2; ; i++ {
+		if _, err := os.Stat(archivePath); os.IsNotExist(err) {
+			break
+		}
+
+		archivePath = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	if _, err := util.MoveFile(item.Path, archivePath, false); err != nil {
+		return "", err
+	}
+
+	return archivePath, nil
+}
@@ -0,0 +1,107 @@
+package library
+
+import (
+	"runtime"
+	"testing"
+	"torrentRenamer"
+	"torrentRenamer/config"
+)
+
+func TestRank(t *testing.T) {
+	sources := []string{"BluRay", "WEB-DL", "HDTV"}
+
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"BluRay", 0},
+		{"bluray", 0},
+		{"Blu-Ray", 0},
+		{"webdl", 1},
+		{"WEB.DL", 1},
+		{"HDTV", 2},
+		{"DVDRip", -1},
+		{"", -1},
+	}
+
+	for _, test := range tests {
+		if got := rank(test.value, sources); got != test.want {
+			t.Errorf("rank(%q) = %d, want %d", test.value, got, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	upgrades := &config.GetConfig().Upgrades
+	upgrades.Resolutions = []string{"2160p", "1080p", "720p"}
+	upgrades.Sources = []string{"BluRay", "WEB-DL", "HDTV"}
+	upgrades.Codecs = []string{"x265", "x264"}
+	upgrades.PreferProper = true
+	upgrades.Size = "larger"
+
+	item := func(resolution string, source string, codec string, proper bool, size int64) Item {
+		return Item{
+			Release: torrentRenamer.Release{Resolution: resolution, Source: source, Codec: codec, Proper: proper},
+			Size:    size,
+		}
+	}
+
+	tests := []struct {
+		name string
+		a    Item
+		b    Item
+		want int
+	}{
+		{"higher resolution", item("1080p", "HDTV", "x264", false, 1), item("720p", "BluRay", "x265", true, 2), 1},
+		{"lower resolution", item("720p", "BluRay", "x265", false, 2), item("1080p", "HDTV", "x264", false, 1), -1},
+		{"better source", item("1080p", "BluRay", "x264", false, 1), item("1080p", "WEB-DL", "x265", false, 2), 1},
+		{"better codec", item("1080p", "BluRay", "x265", false, 1), item("1080p", "BluRay", "x264", false, 2), 1},
+		{"unknown resolution is skipped", item("", "BluRay", "x264", false, 1), item("2160p", "HDTV", "x264", false, 1), 1},
+		{"proper", item("1080p", "BluRay", "x264", true, 1), item("1080p", "BluRay", "x264", false, 2), 1},
+		{"larger", item("1080p", "BluRay", "x264", false, 2), item("1080p", "BluRay", "x264", false, 1), 1},
+		{"smaller", item("1080p", "BluRay", "x264", false, 1), item("1080p", "BluRay", "x264", false, 2), -1},
+		{"equal", item("1080p", "BluRay", "x264", false, 1), item("1080p", "bluray", "X.264", false, 1), 0},
+	}
+
+	for _, test := range tests {
+		got := Compare(test.a, test.b)
+		if (got > 0) != (test.want > 0) || (got < 0) != (test.want < 0) {
+			t.Errorf("%s: Compare() = %d, want the sign of %d", test.name, got, test.want)
+		}
+	}
+
+	upgrades.PreferProper = false
+	upgrades.Size = ""
+
+	if got := Compare(item("1080p", "BluRay", "x264", true, 1), item("1080p", "BluRay", "x264", false, 2)); got != 0 {
+		t.Errorf("Compare() without proper and size preferences = %d, want 0", got)
+	}
+}
+
+func TestGetArchivePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	conf := config.GetConfig()
+	conf.DefaultDirectories.Movies = "/videos/Movies/"
+	conf.DefaultDirectories.Shows = "/videos/TV Shows"
+	conf.Upgrades.ArchiveDir = "/archive"
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/videos/Movies/Heat (1995)/Heat (1995).mkv", "/archive/Movies/Heat (1995)/Heat (1995).mkv"},
+		{"/videos/TV Shows/The Office/Season 01/S01E02.mkv", "/archive/TV Shows/The Office/Season 01/S01E02.mkv"},
+		{"/videos/Movies/..Heat.mkv", "/archive/Movies/..Heat.mkv"},
+		{"/videos/Other/Heat.mkv", "/archive/Heat.mkv"},
+		{"/downloads/Heat.mkv", "/archive/Heat.mkv"},
+	}
+
+	for _, test := range tests {
+		if got := getArchivePath(test.path); got != test.want {
+			t.Errorf("getArchivePath(%s) = %s, want %s", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"torrentRenamer"
+)
+
+var videoExtensions = map[string]bool{
+	".avi":  true,
+	".flv":  true,
+	".m2ts": true,
+	".m4v":  true,
+	".mkv":  true,
+	".mov":  true,
+	".mp4":  true,
+	".mpeg": true,
+	".mpg":  true,
+	".ts":   true,
+	".webm": true,
+	".wmv":  true,
+}
+
+// IsVideo - Returns true if the file has the extension of a video
+func IsVideo(filePath string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// ScanResult - The items a scan added to and removed from the index
+type ScanResult struct {
+	Added   []Item
+	Removed []Item
+}
+
+// Scan - Adds the videos below dirs that are not indexed yet, parsing the names
+// they were given when they were moved, and removes the items whose file is
+// gone
+func (idx *Index) Scan(dirs ...string) (ScanResult, error) {
+	result := ScanResult{Added: make([]Item, 0), Removed: make([]Item, 0)}
+
+	for _, item := range idx.All() {
+		if _, err := os.Stat(item.Path); os.IsNotExist(err) {
+			idx.Remove(item.Path)
+			result.Removed = append(result.Removed, item)
+		}
+	}
+
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() || !IsVideo(filePath) {
+				return nil
+			}
+
+			if _, ok := idx.Get(filePath); ok {
+				return nil
+			}
+
+			video, err := torrentRenamer.ParseTorrentName(info.Name())
+			if err != nil {
+				return nil
+			}
+
+			item, err := NewItem(filePath, video)
+			if err != nil {
+				return err
+			}
+
+			idx.Add(item)
+			result.Added = append(result.Added, item)
+
+			return nil
+		})
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+var (
+	level               = LevelInfo
+	fileLevel           = LevelInfo
+	console   io.Writer = os.Stdout
+	file      *rotatingFile
+	lock      sync.Mutex
+)
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel - Returns the level with the given name (debug, info, warn or
+// error)
+func ParseLevel(name string) (Level, error) {
+	for l, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return l, nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("Unknown log level %s, expected debug, info, warn or error", name)
+}
+
+// SetLevel - Messages below the level are not printed
+func SetLevel(l Level) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	level = l
+}
+
+// SetFileLevel - Messages below the level are not written to the log file
+func SetFileLevel(l Level) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	fileLevel = l
+}
+
+// SetConsole - Sets where messages are printed, stdout by default
+func SetConsole(w io.Writer) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	console = w
+}
+
+// SetFile - Additionally writes messages to the file at path, which is rotated
+// once it grows beyond maxSize bytes, keeping maxFiles old files. An empty path
+// stops writing to a file.
+func SetFile(path string, maxSize int64, maxFiles int) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	f, err := openRotatingFile(path, maxSize, maxFiles)
+	if err != nil {
+		return err
+	}
+
+	file = f
+
+	return nil
+}
+
+// Log - Prints the message and writes it to the log file with a timestamp and
+// its level, each if its level is high enough for them
+func Log(l Level, message string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	message = strings.TrimRight(message, "\n")
+
+	if console != nil && l >= level {
+		fmt.Fprintln(console, message)
+	}
+
+	if file != nil && l >= fileLevel {
+		line := fmt.Sprintf("%s %-5s %s\n", time.Now().Format(time.RFC3339), strings.ToUpper(l.String()), message)
+
+		if _, err := file.Write([]byte(line)); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not write to log file: %s\n", err.Error())
+		}
+	}
+}
+
+func Debugf(format string, args ...interface{}) {
+	Log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func Infof(format string, args ...interface{}) {
+	Log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func Warnf(format string, args ...interface{}) {
+	Log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func Errorf(format string, args ...interface{}) {
+	Log(LevelError, fmt.Sprintf(format, args...))
+}
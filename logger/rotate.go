@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// rotatingFile - A log file that is moved to <path>.1 (and older files to .2,
+// .3, ...) once it grows beyond maxSize
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+
+	return r, r.open()
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file, r.size = file, info.Size()
+
+	return nil
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if r.maxFiles < 1 {
+		os.Remove(r.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+
+		for i := r.maxFiles - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			// Keep logging to the full file rather than not at all
+			if openErr := r.open(); openErr != nil {
+				return openErr
+			}
+
+			return err
+		}
+	}
+
+	return r.open()
+}
+
+func (r *rotatingFile) Write(data []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(data)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
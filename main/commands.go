@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"torrentRenamer/config"
+	"torrentRenamer/events"
+	"torrentRenamer/logger"
+	"torrentRenamer/services"
+)
+
+type command func(args []string) error
+
+var commands = map[string]command{
+	"dupes":       dupesCommand,
+	"import-imdb": importIMDB,
+	"library":     libraryCommand,
+	"missing":     missingCommand,
+	"services":    servicesCommand,
+}
+
+// runCommand - Runs the command named by the first positional argument,
+// returning false if there is no such command and the arguments are files.
+func runCommand(args []string) (bool, error) {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return false, nil
+	}
+
+	return true, cmd(args[1:])
+}
+
+func importIMDB(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: torrentRenamer import-imdb <directory containing the IMDb .tsv.gz dumps>")
+	}
+
+	config := config.GetConfig()
+
+	if err := services.BuildIMDBIndex(args[0], config.Services.Imdb.IndexPath); err != nil {
+		return err
+	}
+
+	logger.Infof("IMDb index written to %s", config.Services.Imdb.IndexPath)
+
+	return nil
+}
+
+// serviceReport - The result of checking a service, as printed with
+// --output=json
+type serviceReport struct {
+	Service      string            `json:"service"`
+	Default      bool              `json:"default"`
+	Capabilities []string          `json:"capabilities"`
+	Available    bool              `json:"available"`
+	Query        string            `json:"query,omitempty"`
+	Result       string            `json:"result,omitempty"`
+	Latency      time.Duration     `json:"-"`
+	LatencyMs    int64             `json:"latencyMs,omitempty"`
+	Quota        map[string]string `json:"quota,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+func printServiceReport(report serviceReport) error {
+	if events.IsJSON() {
+		return json.NewEncoder(os.Stdout).Encode(report)
+	}
+
+	name := report.Service
+	if report.Default {
+		name += " (default)"
+	}
+
+	fmt.Printf("%s\n", name)
+	fmt.Printf("\tCapabilities: %s\n", strings.Join(report.Capabilities, ", "))
+
+	if !report.Available {
+		fmt.Printf("\tAvailable:    no\n")
+		return nil
+	}
+
+	fmt.Printf("\tAvailable:    yes\n")
+	fmt.Printf("\tLookup:       %s -> %s\n", report.Query, report.Result)
+	fmt.Printf("\tLatency:      %s\n", report.Latency.String())
+
+	keys := make([]string, 0, len(report.Quota))
+	for key := range report.Quota {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Printf("\tQuota:        %s: %s\n", key, report.Quota[key])
+	}
+
+	if report.Error != "" {
+		fmt.Printf("\tError:        %s\n", report.Error)
+	}
+
+	return nil
+}
+
+func servicesCommand(args []string) error {
+	if len(args) != 1 || args[0] != "test" {
+		return errors.New("Usage: torrentRenamer services test")
+	}
+
+	failed := 0
+
+	for _, service := range services.GetRegistedServices() {
+		result := services.CheckService(service)
+
+		report := serviceReport{
+			Service:      result.Service,
+			Default:      services.IsDefault(service),
+			Capabilities: service.Capabilities().Names(),
+			Available:    result.Available,
+			Query:        result.Query,
+			Result:       result.Result,
+			Latency:      result.Latency,
+			LatencyMs:    result.Latency.Milliseconds(),
+			Quota:        result.Quota,
+		}
+
+		if result.Err != nil {
+			report.Error = result.Err.Error()
+			failed++
+		}
+
+		if err := printServiceReport(report); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d service(s) failed their lookup", failed)
+	}
+
+	return nil
+}
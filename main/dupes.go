@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"torrentRenamer/config"
+	"torrentRenamer/events"
+	"torrentRenamer/library"
+)
+
+// duplicateGroup - Copies of the same video in the library
+type duplicateGroup struct {
+	Video string         `json:"video"`
+	Items []library.Item `json:"items"`
+}
+
+// getWastedSize - Returns the size of every copy but the largest one
+func getWastedSize(items []library.Item) int64 {
+	var total, largest int64
+
+	for _, item := range items {
+		total += item.Size
+
+		if item.Size > largest {
+			largest = item.Size
+		}
+	}
+
+	return total - largest
+}
+
+func printDuplicateGroup(items []library.Item) error {
+	if events.IsJSON() {
+		return json.NewEncoder(os.Stdout).Encode(duplicateGroup{Video: items[0].String(), Items: items})
+	}
+
+	fmt.Println(items[0].String())
+
+	for i, item := range items {
+		line := "\t" + describeItem(item)
+
+		for _, other := range items[:i] {
+			if library.SameContent(item, other) {
+				line += ", identical to " + other.Path
+				break
+			}
+		}
+
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// dupesCommand - Reports the videos that are in the library more than once
+func dupesCommand(args []string) error {
+	if len(args) != 0 {
+		return errors.New("Usage: torrentRenamer dupes")
+	}
+
+	dirs := config.GetConfig().DefaultDirectories
+
+	index, err := scanLibrary(dirs.Movies, dirs.Shows)
+	if err != nil {
+		return err
+	}
+
+	groups := index.Duplicates()
+	var wasted int64
+
+	for _, items := range groups {
+		wasted += getWastedSize(items)
+
+		if err := printDuplicateGroup(items); err != nil {
+			return err
+		}
+	}
+
+	if !events.IsJSON() {
+		fmt.Printf("%d video(s) are in the library more than once, taking up %s more than needed\n", len(groups), formatSize(wasted))
+	}
+
+	return nil
+}
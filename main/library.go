@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/events"
+	"torrentRenamer/library"
+	"torrentRenamer/logger"
+	"torrentRenamer/notify"
+	"torrentRenamer/util"
+)
+
+const libraryUsage = "Usage: torrentRenamer library list|search <query>|scan"
+
+// formatSize - Returns the size in bytes in a readable unit, e.g. "1.4 GB"
+func formatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// openLibrary - Returns the library index, or nil if it is disabled or could not
+// be read
+func openLibrary() *library.Index {
+	if !config.GetConfig().Library.Enabled {
+		return nil
+	}
+
+	index, err := library.Open()
+	if err != nil {
+		events.Error("", fmt.Sprintf("Could not open the library index: %s", err.Error()), err)
+		return nil
+	}
+
+	return index
+}
+
+// scanLibrary - Returns the library index, including the videos in dirs that
+// are not indexed yet. The index is only read, so commands work without it.
+func scanLibrary(dirs ...string) (*library.Index, error) {
+	index := &library.Index{}
+
+	if config.GetConfig().Library.Enabled {
+		loaded, err := library.Open()
+		if err == nil {
+			index = loaded
+		} else {
+			logger.Warnf("Could not open the library index, only scanning %s: %s", strings.Join(dirs, ", "), err.Error())
+		}
+	}
+
+	if _, err := index.Scan(dirs...); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// getLibraryItem - Describes the video before it is moved, so it can be
+// compared with the copies in the library
+func getLibraryItem(src string, video torrentRenamer.Video) *library.Item {
+	item, err := library.NewItem(src, video)
+	if err != nil {
+		events.Error(src, fmt.Sprintf("Could not compare %s with the library: %s", src, err.Error()), err)
+		return nil
+	}
+
+	return &item
+}
+
+// describeItem - Returns the item's path with its release and size
+func describeItem(item library.Item) string {
+	details := formatSize(item.Size)
+	if release := item.Release.String(); release != "" {
+		details = release + ", " + details
+	}
+
+	return fmt.Sprintf("%s (%s)", item.Path, details)
+}
+
+// dropMissingCopies - Returns the copies that still exist, removing the ones
+// that were deleted or moved since they were indexed from the index
+func dropMissingCopies(index *library.Index, copies []library.Item) []library.Item {
+	ret := make([]library.Item, 0, len(copies))
+
+	for _, other := range copies {
+		if _, err := os.Stat(other.Path); os.IsNotExist(err) {
+			logger.Debugf("%s is no longer in the library, removing it from the index", other.Path)
+
+			if index != nil {
+				index.Remove(other.Path)
+			}
+
+			continue
+		}
+
+		ret = append(ret, other)
+	}
+
+	return ret
+}
+
+// reportDuplicates - Warns about the videos in the library that are the same
+// as the one about to be moved
+func reportDuplicates(index *library.Index, item library.Item) []library.Item {
+	duplicates := dropMissingCopies(index, index.FindDuplicates(item))
+	if len(duplicates) == 0 {
+		return duplicates
+	}
+
+	descriptions := make([]string, 0, len(duplicates))
+
+	for _, duplicate := range duplicates {
+		description := describeItem(duplicate)
+		if library.SameContent(item, duplicate) {
+			description += ", identical"
+		}
+
+		descriptions = append(descriptions, description)
+	}
+
+	events.Emit(events.Event{
+		Type:       events.TypeDuplicate,
+		Source:     item.Path,
+		Duplicates: duplicates,
+		Message:    fmt.Sprintf("%s is already in the library: %s", item.String(), strings.Join(descriptions, "; ")),
+	})
+
+	return duplicates
+}
+
+// getExistingCopies - Returns the duplicates of the video that still exist
+// along with the file at its destination, which would be overwritten
+func getExistingCopies(index *library.Index, dest string, duplicates []library.Item) ([]library.Item, bool) {
+	duplicates = dropMissingCopies(index, duplicates)
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		return duplicates, false
+	}
+
+	for _, duplicate := range duplicates {
+		if duplicate.Path == dest {
+			return duplicates, true
+		}
+	}
+
+	if index != nil {
+		if item, ok := index.Get(dest); ok {
+			return append(duplicates, item), true
+		}
+	}
+
+	return append(duplicates, library.Item{Path: dest, Size: info.Size()}), true
+}
+
+// replaceExistingCopies - With --upgrade, returns the copies of the video that
+// are in the library if the new one is better than all of them, otherwise
+// returns why the new one is skipped. Without it, a file at the destination
+// stops the move, since it would be overwritten. If copies are returned, the
+// move was already confirmed.
+func replaceExistingCopies(index *library.Index, item *library.Item, dest string, duplicates []library.Item) (string, []library.Item, error) {
+	conf := config.GetConfig()
+
+	existing, destExists := getExistingCopies(index, dest, duplicates)
+	if len(existing) == 0 {
+		return "", nil, nil
+	}
+
+	if !conf.Upgrades.Enabled {
+		if destExists {
+			return "", nil, fmt.Errorf("%s already exists, use --upgrade to replace it with a better copy", dest)
+		}
+
+		return "", nil, nil
+	}
+
+	if item == nil {
+		return "", nil, fmt.Errorf("Cannot compare it with %s", existing[0].Path)
+	}
+
+	paths := make([]string, 0, len(existing))
+
+	for _, other := range existing {
+		if library.SameContent(*item, other) {
+			return fmt.Sprintf("%s is identical", other.Path), nil, nil
+		}
+
+		if library.Compare(*item, other) <= 0 {
+			return fmt.Sprintf("%s is at least as good", describeItem(other)), nil, nil
+		}
+
+		paths = append(paths, fmt.Sprintf("'%s'", other.Path))
+	}
+
+	if !conf.RenameWithoutPrompt {
+		prompt := fmt.Sprintf("Archive\n%s\nand move\n'%s'\nto\n'%s'?\n", strings.Join(paths, "\n"), item.Path, dest)
+		if !util.GetYesOrNo(prompt) {
+			return "Replacing the copies in the library was declined", nil, nil
+		}
+	}
+
+	return "", existing, nil
+}
+
+// archivedCopy - A copy of a video that was moved into the archive to make way
+// for a better one
+type archivedCopy struct {
+	Item library.Item
+	Path string
+}
+
+// restoreCopies - Moves archived copies back into the library, after the better
+// entry could not be moved in
+func restoreCopies(archived []archivedCopy) {
+	for _, entry := range archived {
+		if _, err := util.MoveFile(entry.Path, entry.Item.Path, false); err != nil {
+			events.Error(entry.Path, fmt.Sprintf("Could not restore %s from %s: %s", entry.Item.Path, entry.Path, err.Error()), err)
+		}
+	}
+}
+
+// archiveCopies - Moves the copies into the archive, restoring the ones that
+// were already archived if one of them cannot be
+func archiveCopies(copies []library.Item) ([]archivedCopy, error) {
+	archived := make([]archivedCopy, 0, len(copies))
+
+	for _, other := range copies {
+		archivePath, err := library.Archive(other)
+		if err != nil {
+			restoreCopies(archived)
+			return nil, err
+		}
+
+		archived = append(archived, archivedCopy{Item: other, Path: archivePath})
+	}
+
+	return archived, nil
+}
+
+// recordReplacedCopies - Removes the archived copies from the library once the
+// better entry was moved in
+func recordReplacedCopies(index *library.Index, item *library.Item, archived []archivedCopy, summary *notify.Summary) {
+	for _, entry := range archived {
+		if index != nil {
+			index.Remove(entry.Item.Path)
+		}
+
+		summary.Replaced = append(summary.Replaced, notify.Entry{Source: entry.Item.Path, Destination: entry.Path})
+		events.Emit(events.Event{
+			Type:        events.TypeReplace,
+			Source:      entry.Item.Path,
+			Destination: entry.Path,
+			Message:     fmt.Sprintf("Archived %s to %s, %s is better", describeItem(entry.Item), entry.Path, describeItem(*item)),
+		})
+	}
+}
+
+// addToLibrary - Records the moved video, with the size and checksum it has
+// after tags were written into it
+func addToLibrary(index *library.Index, item *library.Item, src string, dest string) {
+	if item == nil {
+		return
+	}
+
+	index.Remove(src)
+	item.Path = dest
+
+	if config.GetConfig().MetadataFiles.Tags {
+		if err := item.Refresh(); err != nil {
+			events.Error(dest, fmt.Sprintf("Could not add %s to the library: %s", dest, err.Error()), err)
+			return
+		}
+	}
+
+	index.Add(*item)
+}
+
+// printItems - Prints one item per line, as JSON with --output=json
+func printItems(items []library.Item) error {
+	if events.IsJSON() {
+		encoder := json.NewEncoder(os.Stdout)
+
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	for _, item := range items {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", item.String(), item.Release.String(), formatSize(item.Size), item.Path)
+	}
+
+	return writer.Flush()
+}
+
+func libraryCommand(args []string) error {
+	if len(args) < 1 {
+		return errors.New(libraryUsage)
+	}
+
+	index, err := library.Open()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		return printItems(index.All())
+	case "search":
+		if len(args) < 2 {
+			return errors.New(libraryUsage)
+		}
+
+		return printItems(index.Search(strings.Join(args[1:], " ")))
+	case "scan":
+		dirs := config.GetConfig().DefaultDirectories
+
+		result, err := index.Scan(dirs.Movies, dirs.Shows)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range result.Removed {
+			logger.Debugf("Removed %s, it no longer exists", item.Path)
+		}
+
+		for _, item := range result.Added {
+			logger.Debugf("Added %s as %s", item.Path, item.String())
+		}
+
+		if err = index.Save(); err != nil {
+			return err
+		}
+
+		logger.Infof("Added %d and removed %d video(s), the library has %d", len(result.Added), len(result.Removed), len(index.Items))
+
+		return nil
+	}
+
+	return errors.New(libraryUsage)
+}
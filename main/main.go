@@ -1,19 +1,59 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
-	"strings"
 	"sync"
 	"torrentRenamer"
+	"torrentRenamer/artwork"
 	"torrentRenamer/config"
+	"torrentRenamer/convert"
+	"torrentRenamer/events"
 	"torrentRenamer/exec"
+	"torrentRenamer/hooks"
+	"torrentRenamer/library"
+	"torrentRenamer/logger"
+	"torrentRenamer/mediaserver"
+	"torrentRenamer/nfo"
+	"torrentRenamer/notify"
 	"torrentRenamer/services"
+	"torrentRenamer/tags"
 	"torrentRenamer/util"
 )
 
-func getParsedVideosBySource(files []string) map[string]torrentRenamer.Video {
+// interruptContext - Returns a context that is cancelled by the first
+// interrupt. Later interrupts exit as usual.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+
+	go func() {
+		select {
+		case <-interrupts:
+			logger.Warnf("Interrupted, giving up on pending requests")
+		case <-ctx.Done():
+		}
+
+		signal.Stop(interrupts)
+		cancel()
+	}()
+
+	return ctx, cancel
+}
+
+// runHook - Runs the hooks of the event, reporting failures without stopping
+func runHook(event string, data hooks.Data) {
+	if err := hooks.Run(event, data); err != nil {
+		events.Error(data.Old, err.Error(), err)
+	}
+}
+
+func getParsedVideosBySource(files []string, summary *notify.Summary) map[string]torrentRenamer.Video {
 	videos := make(map[string]torrentRenamer.Video, len(files))
 
 	for _, file := range files {
@@ -27,123 +67,340 @@ func getParsedVideosBySource(files []string) map[string]torrentRenamer.Video {
 			if err == nil {
 				videos[src] = video
 				videos[src].SetExt(ext)
+
+				events.Emit(events.Event{Type: events.TypeParsed, Source: src, Video: video})
+				runHook(hooks.OnParsed, hooks.Data{Video: video, Old: src})
 			}
+		} else {
+			summary.Failed = append(summary.Failed, notify.Entry{Source: file, Error: err.Error()})
+			events.Emit(events.Event{Type: events.TypeError, Source: file, Error: err.Error()})
+			runHook(hooks.OnFailed, hooks.Data{Old: file, Error: err.Error()})
 		}
 	}
 
 	return videos
 }
 
-func getVideoDestination(v *torrentRenamer.Video) string {
+// getVideoDestination - Returns where the video should be moved, along with the
+// video found by a service (nil if the parsed name is used)
+func getVideoDestination(src string, v *torrentRenamer.Video) (string, torrentRenamer.Video, error) {
 	video := *v
 
-	var serviceResult string
-	var err error
-
 	config := config.GetConfig()
 
-	if serviceResult, err = services.GetDefaultServiceResults(v); err == nil {
+	service, result, serviceResult, err := services.GetDefaultServiceResults(v)
+	if err == nil {
+		dest := util.JoinPaths(config.DefaultDirectories.Shows, serviceResult)
 		if _, ok := video.(*torrentRenamer.Movie); ok {
-			return util.JoinPaths(config.DefaultDirectories.Movies, serviceResult)
+			dest = util.JoinPaths(config.DefaultDirectories.Movies, serviceResult)
 		}
 
-		return util.JoinPaths(config.DefaultDirectories.Shows, serviceResult)
+		events.Emit(events.Event{Type: events.TypeLookup, Source: src, Destination: dest, Service: service.Name(), Video: result})
+
+		return dest, result, nil
+	}
+
+	// The season listing proves the parsed episode number is wrong, so the
+	// parsed name would be too
+	if notFound, ok := err.(*services.EpisodeNotFoundError); ok {
+		return "", nil, notFound
+	}
+
+	// Without a service the air date cannot be turned into a season and
+	// episode, so there is no parsed name to fall back to
+	if show, ok := video.(*torrentRenamer.Show); ok && show.Aired != "" {
+		return "", nil, fmt.Errorf("Could not find the episode of %s that aired on %s: %s", show.Name, show.Aired, err.Error())
+	}
+
+	event := events.Event{Type: events.TypeLookup, Source: src, Error: err.Error()}
+
+	if _, ok := err.(*services.NoServiceError); !ok {
+		event.Type = events.TypeError
+		event.Message = fmt.Sprintf("Could not look up %s, using its parsed name: %s", video.GetName(), err.Error())
+
+		if service != nil {
+			event.Service = service.Name()
+		}
 	}
 
-	return video.GetNewPath()
+	events.Emit(event)
+
+	return video.GetNewPath(), nil, nil
 }
 
-func processConversions(possibleConversions []string) error {
+// processConversions - Converts the videos that are not in the configured
+// format, remuxing them if only their container differs
+func processConversions(possibleConversions []string, summary *notify.Summary, index *library.Index) {
 	config := config.GetConfig()
-	var err error
-
-	for _, dest := range possibleConversions {
-		ext := filepath.Ext(dest)
-		if ext != config.Conversion.Format {
-			if !config.Conversion.AutoConvert {
-				if !util.GetYesOrNo(fmt.Sprintf("Do you want to convert %s to a(n) %s?", dest, config.Conversion.Format)) {
-					break
-				}
-			}
 
-			if !exec.IsCommandInPath(config.Conversion.Converter) {
-				fmt.Printf("Command \"%s\" is not in path, cannot convert", config.Conversion.Converter)
+	for _, old := range possibleConversions {
+		decision := convert.Decide(old)
+		if decision.Action == convert.ActionNone {
+			continue
+		}
+
+		new := convert.GetNewPath(old)
+
+		if !config.Conversion.AutoConvert {
+			if !util.GetYesOrNo(fmt.Sprintf("Do you want to %s %s to a(n) %s, as %s?", decision.Action, old, config.Conversion.Format, decision.Reason)) {
 				continue
 			}
+		}
 
-			var args string
+		action, err := convert.Convert(decision, old, new)
+		if err == nil {
+			summary.Converted = append(summary.Converted, notify.Entry{Source: old, Destination: new})
 
-			old := dest
-			new := old[0:len(old)-len(ext)] + config.Conversion.Format
+			if index != nil {
+				if err := index.Move(old, new); err != nil {
+					events.Error(new, fmt.Sprintf("Could not update %s in the library: %s", new, err.Error()), err)
+				}
+			}
 
-			args, err = util.InsertTemplateData(config.Conversion.ArgsTemplate, struct {
-				Old string
-				New string
-			}{Old: "TR-OLD", New: "TR-NEW"})
+			events.Emit(events.Event{
+				Type:        events.TypeConvert,
+				Source:      old,
+				Destination: new,
+				Message:     fmt.Sprintf("Converted %s to %s (%s), as %s", old, new, action, decision.Reason),
+			})
+			runHook(hooks.OnConverted, hooks.Data{Old: old, New: new})
+		} else {
+			summary.Failed = append(summary.Failed, notify.Entry{Source: old, Destination: new, Error: err.Error()})
+			events.Error(old, fmt.Sprintf("Error converting %s: %s", old, err.Error()), err)
+			runHook(hooks.OnFailed, hooks.Data{Old: old, New: new, Error: err.Error()})
+		}
+	}
+}
 
-			splitArgs := strings.Split(args, " ")
+// writeMetadataFiles - Writes the NFO, artwork and tags of the moved video.
+// Each video has files of its own, so this runs in parallel for the batch.
+func writeMetadataFiles(dest string, result torrentRenamer.Video, found torrentRenamer.Video) {
+	config := config.GetConfig()
 
-			for i, arg := range splitArgs {
-				if arg == "TR-OLD" {
-					splitArgs[i] = old
-				} else if arg == "TR-NEW" {
-					splitArgs[i] = new
-				}
-			}
+	if config.MetadataFiles.Nfo && result != nil {
+		if err := nfo.Write(dest, result); err != nil {
+			events.Error(dest, fmt.Sprintf("Error writing NFO for %s: %s", dest, err.Error()), err)
+		}
+	}
 
-			if err == nil {
-				err = exec.ExecuteCommandWithSTDOutput(config.Conversion.Converter, splitArgs...)
-			}
+	if config.MetadataFiles.Artwork && result != nil {
+		if err := artwork.Download(dest, result, config.MetadataFiles.ForceArtwork); err != nil {
+			events.Error(dest, fmt.Sprintf("Error downloading artwork for %s: %s", dest, err.Error()), err)
 		}
 	}
 
-	return err
+	if config.MetadataFiles.Tags {
+		if err := tags.Write(dest, found); err != nil {
+			events.Error(dest, fmt.Sprintf("Error tagging %s: %s", dest, err.Error()), err)
+		}
+	}
 }
 
-func processVideoRenaming(videos *map[string]torrentRenamer.Video) ([]string, []string) {
+// processVideoRenaming - Moves the videos, adding what happened to each of them
+// to the summary and the moved ones to the library. Lookups and metadata files
+// run in parallel, while moves are made one at a time, as they depend on what
+// is in the library already and may prompt.
+func processVideoRenaming(videos *map[string]torrentRenamer.Video, summary *notify.Summary, index *library.Index) {
 	config := config.GetConfig()
 	var wg sync.WaitGroup
-	var lock sync.RWMutex
+	var lock, moveLock sync.Mutex
+
+	record := func(update func()) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		update()
+	}
+
+	batch := make([]torrentRenamer.Video, 0, len(*videos))
+	for _, video := range *videos {
+		batch = append(batch, video)
+	}
 
-	movedVideos := make([]string, 0)
-	notMovedVideos := make([]string, 0)
+	services.PrepareServiceBatches(batch)
 
 	for src, video := range *videos {
 		wg.Add(1)
 		go func(wg *sync.WaitGroup, src string, video torrentRenamer.Video) {
-			dest := getVideoDestination(&video)
-
-			if path.Clean(src) != path.Clean(dest) {
-				lock.Lock()
-				if err := util.MoveFile(src, dest, !config.RenameWithoutPrompt); err != nil {
-					fmt.Printf("Error moving file: %s", err.Error())
-					movedVideos = append(movedVideos, dest)
-				} else {
-					notMovedVideos = append(notMovedVideos, dest)
-				}
-				lock.Unlock()
-			} else {
-				notMovedVideos = append(notMovedVideos, dest)
+			defer wg.Done()
+
+			dest, result, err := getVideoDestination(src, &video)
+			if err != nil {
+				events.Emit(events.Event{
+					Type:    events.TypeSkip,
+					Source:  src,
+					Error:   err.Error(),
+					Message: fmt.Sprintf("Skipping %s: %s", src, err.Error()),
+				})
+				record(func() {
+					summary.Failed = append(summary.Failed, notify.Entry{Source: src, Error: err.Error()})
+				})
+				runHook(hooks.OnFailed, hooks.Data{Video: video, Old: src, Error: err.Error()})
+				return
+			}
+
+			if path.Clean(src) == path.Clean(dest) {
+				record(func() {
+					summary.Skipped = append(summary.Skipped, notify.Entry{Source: src, Destination: src})
+				})
+				events.Emit(events.Event{Type: events.TypeSkip, Source: src, Destination: dest})
+				return
+			}
+
+			// Videos that were not found by a service still have their parsed
+			// name, season and episode
+			found := video
+			if result != nil {
+				found = result
+			}
+
+			item := getLibraryItem(src, found)
+
+			moveLock.Lock()
+
+			var duplicates []library.Item
+			var archived []archivedCopy
+
+			if index != nil && item != nil {
+				duplicates = reportDuplicates(index, *item)
+			}
+
+			reason, copies, err := replaceExistingCopies(index, item, dest, duplicates)
+			if err == nil && len(copies) > 0 {
+				archived, err = archiveCopies(copies)
+			}
+
+			if err != nil {
+				moveLock.Unlock()
+
+				events.Error(src, fmt.Sprintf("Not moving %s: %s", src, err.Error()), err)
+				record(func() {
+					summary.Failed = append(summary.Failed, notify.Entry{Source: src, Destination: dest, Error: err.Error()})
+				})
+				runHook(hooks.OnFailed, hooks.Data{Video: video, Old: src, New: dest, Error: err.Error()})
+				return
+			}
+
+			if reason != "" {
+				moveLock.Unlock()
+
+				record(func() {
+					summary.Skipped = append(summary.Skipped, notify.Entry{Source: src, Destination: dest, Error: reason})
+				})
+				events.Emit(events.Event{
+					Type:        events.TypeSkip,
+					Source:      src,
+					Destination: dest,
+					Error:       reason,
+					Message:     fmt.Sprintf("Skipping %s: %s", src, reason),
+				})
+				return
+			}
+
+			moved, err := util.MoveFile(src, dest, !config.RenameWithoutPrompt && len(copies) == 0)
+			if err != nil || !moved {
+				restoreCopies(archived)
+			}
+
+			moveLock.Unlock()
+
+			if err != nil {
+				events.Error(src, fmt.Sprintf("Error moving file: %s", err.Error()), err)
+				record(func() {
+					summary.Failed = append(summary.Failed, notify.Entry{Source: src, Destination: dest, Error: err.Error()})
+				})
+				runHook(hooks.OnFailed, hooks.Data{Video: video, Old: src, New: dest, Error: err.Error()})
+				return
+			}
+
+			if !moved {
+				record(func() {
+					summary.Skipped = append(summary.Skipped, notify.Entry{Source: src})
+				})
+				events.Emit(events.Event{Type: events.TypeSkip, Source: src, Destination: dest})
+				return
+			}
+
+			record(func() {
+				recordReplacedCopies(index, item, archived, summary)
+				summary.Moved = append(summary.Moved, notify.Entry{Source: src, Destination: dest})
+			})
+			events.Emit(events.Event{Type: events.TypeMove, Source: src, Destination: dest, Video: found})
+
+			writeMetadataFiles(dest, result, found)
+
+			if index != nil {
+				addToLibrary(index, item, src, dest)
 			}
 
-			wg.Done()
+			runHook(hooks.OnMoved, hooks.Data{Video: found, Old: src, New: dest})
 		}(&wg, src, video)
 	}
 
 	wg.Wait()
-
-	return movedVideos, notMovedVideos
 }
 
 func main() {
+	config.Init()
+	services.RegisterPlugins()
+
 	files := config.GetPositionalArgs()
 
-	videos := getParsedVideosBySource(files)
-	movedVideos, notMovedVideos := processVideoRenaming(&videos)
+	// stdout is reserved for events, so prompts and the output of commands go
+	// to stderr
+	if events.IsJSON() {
+		util.PromptOutput = os.Stderr
+		exec.CommandOutput = os.Stderr
+		logger.SetConsole(os.Stderr)
+	}
 
-	possibleConversions := util.CombineStringArrays(movedVideos, notMovedVideos)
+	if handled, err := runCommand(files); handled {
+		if err != nil {
+			logger.Errorf("%s", err.Error())
+			os.Exit(1)
+		}
 
-	if err := processConversions(possibleConversions); err != nil {
-		fmt.Printf("Error converting video(s): %s", err.Error())
+		return
 	}
+
+	summary := notify.NewSummary()
+	index := openLibrary()
+
+	videos := getParsedVideosBySource(files, summary)
+	processVideoRenaming(&videos, summary, index)
+
+	movedVideos := notify.Destinations(summary.Moved)
+	// Skipped videos that are not in place were rejected or declined, and stay
+	// as they are
+	possibleConversions := util.CombineStringArrays(movedVideos, notify.InPlace(summary.Skipped))
+
+	processConversions(possibleConversions, summary, index)
+
+	if index != nil {
+		if err := index.Save(); err != nil {
+			events.Error("", fmt.Sprintf("Could not save the library index: %s", err.Error()), err)
+		}
+	}
+
+	// Everything is in place by now, so an interrupt only gives up on the
+	// servers and notifiers that are slow to answer
+	ctx, stop := interruptContext()
+	defer stop()
+
+	if err := mediaserver.RefreshAll(ctx, movedVideos); err != nil {
+		events.Error("", err.Error(), err)
+	}
+
+	runHook(hooks.OnBatchComplete, hooks.Data{
+		Moved:     movedVideos,
+		NotMoved:  notify.Sources(summary.Skipped),
+		Failed:    notify.Sources(summary.Failed),
+		Converted: notify.Destinations(summary.Converted),
+	})
+
+	if err := notify.SendAll(ctx, summary); err != nil {
+		events.Error("", err.Error(), err)
+	}
+
+	events.Emit(events.Event{Type: events.TypeSummary, Summary: summary})
 }
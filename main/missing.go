@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"torrentRenamer/config"
+	"torrentRenamer/events"
+	"torrentRenamer/library"
+	"torrentRenamer/logger"
+	"torrentRenamer/match"
+	"torrentRenamer/services"
+)
+
+// showReport - The aired episodes of a show that are not in the library
+type showReport struct {
+	Show    string             `json:"show"`
+	Service string             `json:"service"`
+	Owned   int                `json:"owned"`
+	Aired   int                `json:"aired"`
+	Missing []services.Episode `json:"missing"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// ownedShow - The episodes of a show that are in the library, and the show's
+// IMDb ID if any of them has it
+type ownedShow struct {
+	Name         string
+	SeriesImdbID string
+	Episodes     map[string]bool
+}
+
+func episodeID(season int, episode int) string {
+	return fmt.Sprintf("S%02dE%02d", season, episode)
+}
+
+// getOwnedShows - Returns the shows in the library by their normalized name,
+// combining the index with the videos found in the shows directory
+func getOwnedShows() (map[string]*ownedShow, error) {
+	index, err := scanLibrary(config.GetConfig().DefaultDirectories.Shows)
+	if err != nil {
+		return nil, err
+	}
+
+	shows := make(map[string]*ownedShow)
+
+	for _, item := range index.All() {
+		if item.Type != library.TypeShow {
+			continue
+		}
+
+		key := match.Normalize(item.Name)
+		if _, ok := shows[key]; !ok {
+			shows[key] = &ownedShow{Name: item.Name, Episodes: make(map[string]bool)}
+		}
+
+		if shows[key].SeriesImdbID == "" {
+			shows[key].SeriesImdbID = item.SeriesImdbID
+		}
+
+		shows[key].Episodes[episodeID(item.Season, item.Episode)] = true
+	}
+
+	return shows, nil
+}
+
+func getShowReport(service services.Service, lister services.EpisodeListService, show *ownedShow, now time.Time) showReport {
+	report := showReport{Show: show.Name, Service: service.Name(), Owned: len(show.Episodes), Missing: make([]services.Episode, 0)}
+
+	name, episodes, err := lister.ListEpisodes(show.SeriesImdbID, show.Name)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	report.Show = name
+
+	for _, episode := range episodes {
+		if episode.Season == 0 || !episode.HasAired(now) {
+			continue
+		}
+
+		report.Aired++
+
+		if !show.Episodes[episodeID(episode.Season, episode.Episode)] {
+			report.Missing = append(report.Missing, episode)
+		}
+	}
+
+	return report
+}
+
+func printShowReport(report showReport) error {
+	if events.IsJSON() {
+		return json.NewEncoder(os.Stdout).Encode(report)
+	}
+
+	if len(report.Missing) == 0 {
+		fmt.Printf("%s: all %d aired episodes are in the library\n", report.Show, report.Aired)
+		return nil
+	}
+
+	fmt.Printf("%s: %d of %d aired episodes are missing\n", report.Show, len(report.Missing), report.Aired)
+
+	for _, episode := range report.Missing {
+		line := "\t" + episodeID(episode.Season, episode.Episode)
+
+		if episode.Title != "" {
+			line += " - " + episode.Title
+		}
+
+		if !episode.Aired.IsZero() {
+			line += fmt.Sprintf(" (%s)", episode.Aired.Format("2006-01-02"))
+		} else if episode.Year != 0 {
+			line += fmt.Sprintf(" (%d)", episode.Year)
+		}
+
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// missingCommand - Reports the aired episodes missing from the given shows, or
+// from every show in the library
+func missingCommand(args []string) error {
+	service, lister, err := services.GetEpisodeListService()
+	if err != nil {
+		return err
+	}
+
+	owned, err := getOwnedShows()
+	if err != nil {
+		return err
+	}
+
+	shows := make([]*ownedShow, 0, len(owned))
+
+	if len(args) == 0 {
+		for _, show := range owned {
+			shows = append(shows, show)
+		}
+	} else {
+		// Shows that are not in the library at all are missing every episode
+		for _, name := range args {
+			show, ok := owned[match.Normalize(name)]
+			if !ok {
+				show = &ownedShow{Name: name, Episodes: make(map[string]bool)}
+			}
+
+			shows = append(shows, show)
+		}
+	}
+
+	sort.Slice(shows, func(i, j int) bool {
+		return strings.ToLower(shows[i].Name) < strings.ToLower(shows[j].Name)
+	})
+
+	now := time.Now()
+	failed := 0
+
+	for _, show := range shows {
+		logger.Debugf("Listing the episodes of %s with %s", show.Name, service.Name())
+
+		report := getShowReport(service, lister, show, now)
+
+		if report.Error != "" {
+			failed++
+
+			if !events.IsJSON() {
+				logger.Errorf("Could not list the episodes of %s: %s", show.Name, report.Error)
+				continue
+			}
+		}
+
+		if err := printShowReport(report); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Could not list the episodes of %d show(s)", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package match
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultThreshold     = 0.85
+	DefaultYearTolerance = 1
+	// adjacentYearPenalty keeps an exact year ahead of an otherwise equal
+	// candidate released a year earlier or later
+	adjacentYearPenalty = 0.05
+)
+
+type Candidate struct {
+	Title string
+	Year  int
+}
+
+var articles = map[string]bool{
+	"the": true,
+	"a":   true,
+	"an":  true,
+}
+
+var diacritics = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z", 'þ': "th", 'ð': "d",
+}
+
+// Normalize - Reduces a title to lower case words without diacritics,
+// punctuation or leading articles, with "&" spelled as "and"
+func Normalize(title string) string {
+	var builder strings.Builder
+
+	for _, r := range strings.ToLower(title) {
+		if folded, ok := diacritics[r]; ok {
+			builder.WriteString(folded)
+		} else if r == '&' {
+			builder.WriteString(" and ")
+		} else if r == '\'' || r == '’' {
+			// "Don't" and "Dont" are the same title
+			continue
+		} else if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(builder.String())
+	if len(words) > 1 && articles[words[0]] {
+		words = words[1:]
+	}
+
+	return strings.Join(words, " ")
+}
+
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	ret := values[0]
+
+	for _, value := range values[1:] {
+		if value < ret {
+			ret = value
+		}
+	}
+
+	return ret
+}
+
+// Similarity - Returns how similar two titles are once normalized, from 0
+// (nothing in common) to 1 (the same)
+func Similarity(a string, b string) float64 {
+	runesA, runesB := []rune(Normalize(a)), []rune(Normalize(b))
+
+	longest := len(runesA)
+	if len(runesB) > longest {
+		longest = len(runesB)
+	}
+
+	if longest == 0 {
+		return 0
+	}
+
+	return 1 - float64(levenshtein(runesA, runesB))/float64(longest)
+}
+
+// Score - Rates a candidate against the searched title and year. Candidates
+// further than yearTolerance years away score 0, unknown years are not
+// penalized.
+func Score(title string, year int, candidate Candidate, yearTolerance int) float64 {
+	score := Similarity(title, candidate.Title)
+
+	if year != 0 && candidate.Year != 0 && year != candidate.Year {
+		distance := year - candidate.Year
+		if distance < 0 {
+			distance = -distance
+		}
+
+		if distance > yearTolerance {
+			return 0
+		}
+
+		score -= adjacentYearPenalty
+	}
+
+	return score
+}
+
+// Best - Returns the index and score of the best scoring candidate, or -1 if
+// none reaches the threshold
+func Best(title string, year int, candidates []Candidate, threshold float64, yearTolerance int) (int, float64) {
+	best, bestScore := -1, 0.0
+
+	for i, candidate := range candidates {
+		score := Score(title, year, candidate, yearTolerance)
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if bestScore < threshold {
+		return -1, bestScore
+	}
+
+	return best, bestScore
+}
@@ -0,0 +1,107 @@
+package match
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"The Office", "office"},
+		{"the office (US)", "office us"},
+		{"Amélie", "amelie"},
+		{"Fast & Furious", "fast and furious"},
+		{"Don't Look Up", "dont look up"},
+		{"Don’t Look Up", "dont look up"},
+		{"Mr. Robot", "mr robot"},
+		{"Marvel's Agents of S.H.I.E.L.D.", "marvels agents of s h i e l d"},
+		{"The", "the"},
+		{"A Quiet Place", "quiet place"},
+		{"Æon Flux", "aeon flux"},
+		{"  Spaced  Out  ", "spaced out"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := Normalize(test.title); got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"office", "office", 0},
+		{"amélie", "amelie", 1},
+	}
+
+	for _, test := range tests {
+		if got := levenshtein([]rune(test.a), []rune(test.b)); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want float64
+	}{
+		{"The Office", "Office", 1},
+		{"Amélie", "Amelie", 1},
+		{"Fast & Furious", "Fast and Furious", 1},
+		{"The Matrix", "The Matrix Reloaded", 6.0 / 15},
+		{"Heat", "Matrix", 1.0 / 6},
+		{"", "", 0},
+	}
+
+	for _, test := range tests {
+		if got := Similarity(test.a, test.b); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %f, want %f", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestBest(t *testing.T) {
+	candidates := []Candidate{
+		{Title: "The Matrix Reloaded", Year: 2003},
+		{Title: "The Matrix", Year: 1999},
+		{Title: "The Matrix", Year: 2000},
+	}
+
+	tests := []struct {
+		title     string
+		year      int
+		tolerance int
+		want      int
+	}{
+		{"The Matrix", 1999, DefaultYearTolerance, 1},
+		{"The Matrix", 2000, DefaultYearTolerance, 2},
+		{"The Matrix", 0, DefaultYearTolerance, 1},
+		{"The Matrix", 2001, DefaultYearTolerance, 2},
+		{"The Matrix", 2010, DefaultYearTolerance, -1},
+		{"Matrix Reloaded", 2003, DefaultYearTolerance, 0},
+		{"Heat", 1995, DefaultYearTolerance, -1},
+		{"The Matrix", 1999, 0, 1},
+		{"The Matrix", 2001, 0, -1},
+	}
+
+	for _, test := range tests {
+		if got, score := Best(test.title, test.year, candidates, DefaultThreshold, test.tolerance); got != test.want {
+			t.Errorf("Best(%q, %d) = %d (%f), want %d", test.title, test.year, got, score, test.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package mediaserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"torrentRenamer/fetch"
+)
+
+type jellyfinUpdate struct {
+	Path       string `json:"Path"`
+	UpdateType string `json:"UpdateType"`
+}
+
+type jellyfinUpdates struct {
+	Updates []jellyfinUpdate `json:"Updates"`
+}
+
+// jellyfinServer - Jellyfin kept Emby's API for reporting changed media, so
+// both are refreshed the same way
+type jellyfinServer struct {
+	name  string
+	url   string
+	token string
+	paths pathMapper
+}
+
+func (j *jellyfinServer) Name() string {
+	return j.name
+}
+
+// Refresh - Reports the folders as modified, which makes the server scan only
+// those folders
+func (j *jellyfinServer) Refresh(ctx context.Context, folders []string) error {
+	payload := jellyfinUpdates{Updates: make([]jellyfinUpdate, len(folders))}
+
+	for i, folder := range folders {
+		payload.Updates[i] = jellyfinUpdate{Path: j.paths.toServer(folder), UpdateType: "Modified"}
+	}
+
+	header := http.Header{}
+	header.Set("X-Emby-Token", j.token)
+
+	if _, err := fetch.PostJSONContext(ctx, j.url+"/Library/Media/Updated", header, payload); err != nil {
+		return fmt.Errorf("%s: %s", j.name, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package mediaserver
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"torrentRenamer/config"
+	"torrentRenamer/logger"
+)
+
+const (
+	TypePlex     = "plex"
+	TypeJellyfin = "jellyfin"
+	TypeEmby     = "emby"
+)
+
+// Server - A media server whose library can be told about changed folders
+type Server interface {
+	Name() string
+	Refresh(ctx context.Context, folders []string) error
+}
+
+// NewServer - Returns the server for an entry of the mediaServers config section
+func NewServer(name string, serverType string, url string, token string, pathMappings map[string]string) (Server, error) {
+	url = strings.TrimSuffix(url, "/")
+
+	if name == "" {
+		name = url
+	}
+
+	mapper := pathMapper(pathMappings)
+
+	switch strings.ToLower(serverType) {
+	case TypePlex:
+		return &plexServer{name: name, url: url, token: token, paths: mapper}, nil
+	case TypeJellyfin, TypeEmby:
+		return &jellyfinServer{name: name, url: url, token: token, paths: mapper}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown type \"%s\" of media server %s, expected %s, %s or %s", serverType, name, TypePlex, TypeJellyfin, TypeEmby)
+}
+
+// pathMapper - Maps local path prefixes to the paths the server sees the same
+// folders at, e.g. when it runs in a container
+type pathMapper map[string]string
+
+func (m pathMapper) toServer(folder string) string {
+	bestLocal, bestServer, found := "", "", false
+
+	for local, server := range m {
+		local = strings.TrimSuffix(local, "/")
+
+		if (folder == local || strings.HasPrefix(folder, local+"/")) && (!found || len(local) > len(bestLocal)) {
+			bestLocal, bestServer, found = local, strings.TrimSuffix(server, "/"), true
+		}
+	}
+
+	if !found {
+		return folder
+	}
+
+	return bestServer + strings.TrimPrefix(folder, bestLocal)
+}
+
+// GetFolders - Returns the folders the given files are in, without duplicates
+func GetFolders(files []string) []string {
+	seen := make(map[string]bool)
+	ret := make([]string, 0)
+
+	for _, file := range files {
+		folder := filepath.Dir(file)
+		if !seen[folder] {
+			seen[folder] = true
+			ret = append(ret, folder)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// RefreshAll - Asks every configured media server to rescan the folders the
+// given files are in. Returns an error describing every server that failed.
+func RefreshAll(ctx context.Context, files []string) error {
+	conf := config.GetConfig()
+
+	if len(files) == 0 || len(conf.MediaServers) == 0 {
+		return nil
+	}
+
+	folders := GetFolders(files)
+	failed := make([]string, 0)
+
+	for _, serverConfig := range conf.MediaServers {
+		server, err := NewServer(serverConfig.Name, serverConfig.Type, serverConfig.Url, serverConfig.Token, serverConfig.PathMappings)
+		if err == nil {
+			err = server.Refresh(ctx, folders)
+		}
+
+		if err != nil {
+			failed = append(failed, err.Error())
+		} else {
+			logger.Infof("Asked %s to refresh %d folder(s)", server.Name(), len(folders))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Could not refresh media server(s): %s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
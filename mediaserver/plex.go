@@ -0,0 +1,105 @@
+package mediaserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"torrentRenamer/fetch"
+)
+
+type plexLocation struct {
+	Path string `json:"path"`
+}
+
+type plexSection struct {
+	Key      string         `json:"key"`
+	Title    string         `json:"title"`
+	Location []plexLocation `json:"Location"`
+}
+
+type plexSectionsResponse struct {
+	MediaContainer struct {
+		Directory []plexSection `json:"Directory"`
+	} `json:"MediaContainer"`
+}
+
+type plexServer struct {
+	name  string
+	url   string
+	token string
+	paths pathMapper
+}
+
+func (p *plexServer) Name() string {
+	return p.name
+}
+
+func (p *plexServer) getHeader() http.Header {
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	header.Set("X-Plex-Token", p.token)
+
+	return header
+}
+
+func (p *plexServer) getSections(ctx context.Context) ([]plexSection, error) {
+	body, err := fetch.GetWithHeaderContext(ctx, p.url+"/library/sections", p.getHeader())
+	if err != nil {
+		return nil, err
+	}
+
+	var res plexSectionsResponse
+	if err = json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+
+	return res.MediaContainer.Directory, nil
+}
+
+// findSection - Returns the key of the library section with the location that
+// contains the folder most closely
+func findSection(sections []plexSection, folder string) (string, bool) {
+	key, longest := "", -1
+
+	for _, section := range sections {
+		for _, location := range section.Location {
+			path := strings.TrimSuffix(location.Path, "/")
+
+			if (folder == path || strings.HasPrefix(folder, path+"/")) && len(path) > longest {
+				key, longest = section.Key, len(path)
+			}
+		}
+	}
+
+	return key, longest >= 0
+}
+
+// Refresh - Runs a partial scan of the library section each folder is in
+func (p *plexServer) Refresh(ctx context.Context, folders []string) error {
+	sections, err := p.getSections(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %s", p.name, err.Error())
+	}
+
+	for _, folder := range folders {
+		folder = p.paths.toServer(folder)
+
+		key, ok := findSection(sections, folder)
+		if !ok {
+			return fmt.Errorf("%s: No library section contains %s", p.name, folder)
+		}
+
+		query := url.Values{}
+		query.Set("path", folder)
+
+		requestURL := fmt.Sprintf("%s/library/sections/%s/refresh?%s", p.url, url.PathEscape(key), query.Encode())
+		if _, err = fetch.GetWithHeaderContext(ctx, requestURL, p.getHeader()); err != nil {
+			return fmt.Errorf("%s: %s", p.name, err.Error())
+		}
+	}
+
+	return nil
+}
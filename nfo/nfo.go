@@ -0,0 +1,157 @@
+package nfo
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/util"
+)
+
+const (
+	header = `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>` + "\n"
+)
+
+type uniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+type movie struct {
+	XMLName       xml.Name   `xml:"movie"`
+	Title         string     `xml:"title"`
+	OriginalTitle string     `xml:"originaltitle,omitempty"`
+	Year          int        `xml:"year,omitempty"`
+	Plot          string     `xml:"plot,omitempty"`
+	Runtime       int        `xml:"runtime,omitempty"`
+	Mpaa          string     `xml:"mpaa,omitempty"`
+	Genres        []string   `xml:"genre"`
+	Director      string     `xml:"director,omitempty"`
+	Country       string     `xml:"country,omitempty"`
+	UniqueIDs     []uniqueID `xml:"uniqueid"`
+}
+
+type tvShow struct {
+	XMLName   xml.Name   `xml:"tvshow"`
+	Title     string     `xml:"title"`
+	Genres    []string   `xml:"genre"`
+	UniqueIDs []uniqueID `xml:"uniqueid"`
+}
+
+type episodeDetails struct {
+	XMLName   xml.Name   `xml:"episodedetails"`
+	Title     string     `xml:"title"`
+	ShowTitle string     `xml:"showtitle"`
+	Season    int        `xml:"season"`
+	Episode   int        `xml:"episode"`
+	Plot      string     `xml:"plot,omitempty"`
+	Runtime   int        `xml:"runtime,omitempty"`
+	Director  string     `xml:"director,omitempty"`
+	UniqueIDs []uniqueID `xml:"uniqueid"`
+}
+
+// uniqueIDs - Kodi uses the default ID to scrape the video, which is IMDb's
+// when there is one
+func uniqueIDs(imdbID string, tmdbID string) []uniqueID {
+	ret := make([]uniqueID, 0, 2)
+
+	if imdbID != "" {
+		ret = append(ret, uniqueID{Type: "imdb", Default: true, Value: imdbID})
+	}
+
+	if tmdbID != "" {
+		ret = append(ret, uniqueID{Type: "tmdb", Default: imdbID == "", Value: tmdbID})
+	}
+
+	return ret
+}
+
+func writeFile(path string, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return util.WriteFile(path, append([]byte(header), append(data, '\n')...))
+}
+
+// basePath - Returns the path of the video without its extension
+func basePath(videoPath string) string {
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+}
+
+// movieFileName - Movies in their own folder get a movie.nfo, otherwise the NFO
+// is named after the video so several movies can share a folder
+func movieFileName(moviePath string) string {
+	dir := filepath.Dir(moviePath)
+
+	if filepath.Clean(dir) == filepath.Clean(config.GetConfig().DefaultDirectories.Movies) {
+		return basePath(moviePath) + ".nfo"
+	}
+
+	return filepath.Join(dir, "movie.nfo")
+}
+
+func writeMovie(moviePath string, m *torrentRenamer.Movie) error {
+	originalTitle := m.OriginalName
+	if originalTitle == m.Name {
+		originalTitle = ""
+	}
+
+	return writeFile(movieFileName(moviePath), movie{
+		Title:         m.Name,
+		OriginalTitle: originalTitle,
+		Year:          m.Year,
+		Plot:          m.Plot,
+		Runtime:       m.Runtime,
+		Mpaa:          m.Rated,
+		Genres:        m.Genres,
+		Director:      m.Director,
+		Country:       m.Country,
+		UniqueIDs:     uniqueIDs(m.ImdbID, m.TmdbID),
+	})
+}
+
+// writeShow - Writes the episode's NFO, and the show's tvshow.nfo if the show
+// does not have one yet
+func writeShow(episodePath string, s *torrentRenamer.Show) error {
+	showPath := filepath.Join(torrentRenamer.GetShowDirectory(episodePath), "tvshow.nfo")
+
+	if _, err := os.Stat(showPath); os.IsNotExist(err) {
+		err = writeFile(showPath, tvShow{
+			Title:     s.Name,
+			Genres:    s.Genres,
+			UniqueIDs: uniqueIDs(s.SeriesImdbID, ""),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFile(basePath(episodePath)+".nfo", episodeDetails{
+		Title:     s.Title,
+		ShowTitle: s.Name,
+		Season:    s.Season,
+		Episode:   s.Episode,
+		Plot:      s.Plot,
+		Runtime:   s.Runtime,
+		Director:  s.Director,
+		UniqueIDs: uniqueIDs(s.ImdbID, s.TmdbID),
+	})
+}
+
+// Write - Writes the Kodi NFO file(s) for a video found by a service, next to
+// where the video was moved
+func Write(videoPath string, video torrentRenamer.Video) error {
+	switch v := video.(type) {
+	case *torrentRenamer.Movie:
+		return writeMovie(videoPath, v)
+	case *torrentRenamer.Show:
+		return writeShow(videoPath, v)
+	}
+
+	return nil
+}
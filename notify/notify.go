@@ -0,0 +1,239 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"torrentRenamer/config"
+	"torrentRenamer/logger"
+)
+
+const (
+	TypeWebhook = "webhook"
+	TypeNtfy    = "ntfy"
+	TypeGotify  = "gotify"
+	TypeDiscord = "discord"
+	TypeSlack   = "slack"
+	TypeSMTP    = "smtp"
+)
+
+// Entry - What happened to a single video of a batch
+type Entry struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Summary - Everything that happened in a batch. Skipped videos were not moved
+// because they already were in place, moving them was declined or the library
+// has a copy that is at least as good. Replaced videos were moved from the
+// library into the archive because a better copy was moved in.
+type Summary struct {
+	Moved     []Entry `json:"moved"`
+	Skipped   []Entry `json:"skipped"`
+	Failed    []Entry `json:"failed"`
+	Converted []Entry `json:"converted"`
+	Replaced  []Entry `json:"replaced"`
+}
+
+// Options - The settings of a notifier from the notifiers config section
+type Options struct {
+	Name     string
+	Type     string
+	URL      string
+	Token    string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Notifier - Sends batch summaries somewhere people will see them
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, summary *Summary) error
+}
+
+// NewSummary - Returns an empty summary that entries can be added to
+func NewSummary() *Summary {
+	return &Summary{
+		Moved:     make([]Entry, 0),
+		Skipped:   make([]Entry, 0),
+		Failed:    make([]Entry, 0),
+		Converted: make([]Entry, 0),
+		Replaced:  make([]Entry, 0),
+	}
+}
+
+// Sources - Returns the sources of the entries
+func Sources(entries []Entry) []string {
+	ret := make([]string, len(entries))
+	for i, entry := range entries {
+		ret[i] = entry.Source
+	}
+
+	return ret
+}
+
+// Destinations - Returns the destinations of the entries
+func Destinations(entries []Entry) []string {
+	ret := make([]string, len(entries))
+	for i, entry := range entries {
+		ret[i] = entry.Destination
+	}
+
+	return ret
+}
+
+// InPlace - Returns the sources of the entries that already were at their
+// destination
+func InPlace(entries []Entry) []string {
+	ret := make([]string, 0)
+	for _, entry := range entries {
+		if entry.Source == entry.Destination {
+			ret = append(ret, entry.Source)
+		}
+	}
+
+	return ret
+}
+
+func (s *Summary) IsEmpty() bool {
+	return len(s.Moved)+len(s.Skipped)+len(s.Failed)+len(s.Converted)+len(s.Replaced) == 0
+}
+
+func (s *Summary) HasFailures() bool {
+	return len(s.Failed) > 0
+}
+
+// Title - Returns a one line overview, e.g. "torrentRenamer: 3 moved, 1 failed"
+func (s *Summary) Title() string {
+	counts := make([]string, 0, 5)
+
+	for _, count := range []struct {
+		name    string
+		entries []Entry
+	}{
+		{"moved", s.Moved},
+		{"skipped", s.Skipped},
+		{"failed", s.Failed},
+		{"converted", s.Converted},
+		{"replaced", s.Replaced},
+	} {
+		if len(count.entries) > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", len(count.entries), count.name))
+		}
+	}
+
+	return "torrentRenamer: " + strings.Join(counts, ", ")
+}
+
+func writeSection(builder *strings.Builder, title string, entries []Entry, line func(Entry) string) {
+	if len(entries) == 0 {
+		return
+	}
+
+	if builder.Len() > 0 {
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString(title + ":\n")
+
+	for _, entry := range entries {
+		builder.WriteString("- " + line(entry) + "\n")
+	}
+}
+
+// Text - Returns the summary as plain text, listing every video
+func (s *Summary) Text() string {
+	var builder strings.Builder
+
+	writeSection(&builder, "Failed", s.Failed, func(e Entry) string {
+		return fmt.Sprintf("%s: %s", filepath.Base(e.Source), e.Error)
+	})
+	writeSection(&builder, "Moved", s.Moved, func(e Entry) string {
+		return fmt.Sprintf("%s -> %s", filepath.Base(e.Source), e.Destination)
+	})
+	writeSection(&builder, "Converted", s.Converted, func(e Entry) string {
+		return e.Destination
+	})
+	writeSection(&builder, "Replaced", s.Replaced, func(e Entry) string {
+		return fmt.Sprintf("%s -> %s", e.Source, e.Destination)
+	})
+	writeSection(&builder, "Skipped", s.Skipped, func(e Entry) string {
+		return e.Source
+	})
+
+	return builder.String()
+}
+
+// NewNotifier - Returns the notifier for an entry of the notifiers config
+// section
+func NewNotifier(options Options) (Notifier, error) {
+	if options.Name == "" {
+		options.Name = options.Type
+	}
+
+	switch strings.ToLower(options.Type) {
+	case TypeWebhook:
+		return &webhookNotifier{options}, nil
+	case TypeNtfy:
+		return &ntfyNotifier{options}, nil
+	case TypeGotify:
+		return &gotifyNotifier{options}, nil
+	case TypeDiscord:
+		return &discordNotifier{options}, nil
+	case TypeSlack:
+		return &slackNotifier{options}, nil
+	case TypeSMTP:
+		return &smtpNotifier{options}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown type \"%s\" of notifier %s, expected %s, %s, %s, %s, %s or %s", options.Type, options.Name, TypeWebhook, TypeNtfy, TypeGotify, TypeDiscord, TypeSlack, TypeSMTP)
+}
+
+// SendAll - Sends the summary to every configured notifier, skipping those
+// that only want to hear about failures if nothing failed. Returns an error
+// describing every notifier that failed.
+func SendAll(ctx context.Context, summary *Summary) error {
+	conf := config.GetConfig()
+
+	if summary.IsEmpty() {
+		return nil
+	}
+
+	failed := make([]string, 0)
+
+	for _, notifierConfig := range conf.Notifiers {
+		if notifierConfig.OnlyFailures && !summary.HasFailures() {
+			continue
+		}
+
+		notifier, err := NewNotifier(Options{
+			Name:     notifierConfig.Name,
+			Type:     notifierConfig.Type,
+			URL:      notifierConfig.Url,
+			Token:    notifierConfig.Token,
+			Username: notifierConfig.Username,
+			Password: notifierConfig.Password,
+			From:     notifierConfig.From,
+			To:       notifierConfig.To,
+		})
+		if err == nil {
+			err = notifier.Notify(ctx, summary)
+		}
+
+		if err != nil {
+			failed = append(failed, err.Error())
+		} else {
+			logger.Debugf("Sent batch summary to %s", notifier.Name())
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Could not send notification(s): %s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpNotifier - Emails the summary through the server at the URL, given as
+// host:port
+type smtpNotifier struct {
+	options Options
+}
+
+func (s *smtpNotifier) Name() string {
+	return s.options.Name
+}
+
+func (s *smtpNotifier) getMessage(summary *Summary) []byte {
+	var builder strings.Builder
+
+	headers := [][2]string{
+		{"From", s.options.From},
+		{"To", strings.Join(s.options.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("UTF-8", summary.Title())},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=UTF-8"},
+	}
+
+	for _, header := range headers {
+		builder.WriteString(fmt.Sprintf("%s: %s\r\n", header[0], header[1]))
+	}
+
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.Replace(summary.Text(), "\n", "\r\n", -1))
+
+	return []byte(builder.String())
+}
+
+func (s *smtpNotifier) Notify(ctx context.Context, summary *Summary) error {
+	if s.options.From == "" || len(s.options.To) == 0 {
+		return wrapError(s.options.Name, errors.New("Email notifiers need a from address and at least one to address"))
+	}
+
+	// SendMail cannot be cancelled once it started
+	if err := ctx.Err(); err != nil {
+		return wrapError(s.options.Name, err)
+	}
+
+	var auth smtp.Auth
+	if s.options.Username != "" {
+		host, _, err := net.SplitHostPort(s.options.URL)
+		if err != nil {
+			return wrapError(s.options.Name, err)
+		}
+
+		auth = smtp.PlainAuth("", s.options.Username, s.options.Password, host)
+	}
+
+	err := smtp.SendMail(s.options.URL, auth, s.options.From, s.options.To, s.getMessage(summary))
+
+	return wrapError(s.options.Name, err)
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"torrentRenamer/fetch"
+)
+
+const (
+	// discordMessageLimit - Discord rejects messages with more characters
+	discordMessageLimit = 2000
+	// slackMessageLimit - Slack truncates longer messages itself, but splits
+	// them into several first
+	slackMessageLimit = 4000
+)
+
+type webhookPayload struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	*Summary
+}
+
+type gotifyPayload struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+type discordPayload struct {
+	Content string `json:"content"`
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// webhookNotifier - Posts the summary as JSON, for custom receivers
+type webhookNotifier struct {
+	options Options
+}
+
+// ntfyNotifier - Publishes to the topic in the URL, e.g. https://ntfy.sh/mytopic
+type ntfyNotifier struct {
+	options Options
+}
+
+type gotifyNotifier struct {
+	options Options
+}
+
+// discordNotifier - Posts to a Discord webhook
+type discordNotifier struct {
+	options Options
+}
+
+// slackNotifier - Posts to a Slack incoming webhook, or anything compatible
+// like Mattermost or Rocket.Chat
+type slackNotifier struct {
+	options Options
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
+
+func bearerHeader(token string) http.Header {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	return header
+}
+
+func wrapError(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s", name, err.Error())
+}
+
+func (w *webhookNotifier) Name() string {
+	return w.options.Name
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, summary *Summary) error {
+	payload := webhookPayload{Title: summary.Title(), Text: summary.Text(), Summary: summary}
+
+	_, err := fetch.PostJSONContext(ctx, w.options.URL, bearerHeader(w.options.Token), payload)
+
+	return wrapError(w.options.Name, err)
+}
+
+func (n *ntfyNotifier) Name() string {
+	return n.options.Name
+}
+
+func (n *ntfyNotifier) Notify(ctx context.Context, summary *Summary) error {
+	header := bearerHeader(n.options.Token)
+	header.Set("Title", summary.Title())
+
+	if summary.HasFailures() {
+		header.Set("Priority", "high")
+		header.Set("Tags", "warning")
+	} else {
+		header.Set("Tags", "movie_camera")
+	}
+
+	_, err := fetch.PostContext(ctx, n.options.URL, header, []byte(summary.Text()))
+
+	return wrapError(n.options.Name, err)
+}
+
+func (g *gotifyNotifier) Name() string {
+	return g.options.Name
+}
+
+func (g *gotifyNotifier) Notify(ctx context.Context, summary *Summary) error {
+	header := http.Header{}
+	header.Set("X-Gotify-Key", g.options.Token)
+
+	priority := 5
+	if summary.HasFailures() {
+		priority = 8
+	}
+
+	payload := gotifyPayload{Title: summary.Title(), Message: summary.Text(), Priority: priority}
+
+	_, err := fetch.PostJSONContext(ctx, strings.TrimSuffix(g.options.URL, "/")+"/message", header, payload)
+
+	return wrapError(g.options.Name, err)
+}
+
+func (d *discordNotifier) Name() string {
+	return d.options.Name
+}
+
+func (d *discordNotifier) Notify(ctx context.Context, summary *Summary) error {
+	content := fmt.Sprintf("**%s**\n%s", summary.Title(), summary.Text())
+
+	_, err := fetch.PostJSONContext(ctx, d.options.URL, nil, discordPayload{Content: truncate(content, discordMessageLimit)})
+
+	return wrapError(d.options.Name, err)
+}
+
+func (s *slackNotifier) Name() string {
+	return s.options.Name
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, summary *Summary) error {
+	text := fmt.Sprintf("*%s*\n%s", summary.Title(), summary.Text())
+
+	_, err := fetch.PostJSONContext(ctx, s.options.URL, nil, slackPayload{Text: truncate(text, slackMessageLimit)})
+
+	return wrapError(s.options.Name, err)
+}
@@ -0,0 +1,106 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"torrentRenamer/exec"
+)
+
+const (
+	prober = "ffprobe"
+
+	TypeVideo    = "video"
+	TypeAudio    = "audio"
+	TypeSubtitle = "subtitle"
+)
+
+// containerNames - The names ffprobe gives the containers of file formats
+var containerNames = map[string]string{
+	"avi":  "avi",
+	"m4v":  "mp4",
+	"mkv":  "matroska",
+	"mov":  "mov",
+	"mp4":  "mp4",
+	"mpg":  "mpeg",
+	"mpeg": "mpeg",
+	"ts":   "mpegts",
+	"webm": "webm",
+	"wmv":  "asf",
+}
+
+// Stream - A video, audio or subtitle stream of a file
+type Stream struct {
+	Index     int    `json:"index"`
+	CodecType string `json:"codec_type"`
+	CodecName string `json:"codec_name"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Channels  int    `json:"channels,omitempty"`
+}
+
+// Format - The container of a file. FormatName lists every name of the
+// container, e.g. "matroska,webm".
+type Format struct {
+	FormatName string `json:"format_name"`
+	Duration   string `json:"duration"`
+	Size       string `json:"size"`
+	BitRate    string `json:"bit_rate"`
+}
+
+// Result - What ffprobe found out about a file
+type Result struct {
+	Format  Format   `json:"format"`
+	Streams []Stream `json:"streams"`
+}
+
+// IsAvailable - Returns true if ffprobe is in path
+func IsAvailable() bool {
+	return exec.IsCommandInPath(prober)
+}
+
+// Probe - Inspects the container and streams of the file with ffprobe
+func Probe(filePath string) (*Result, error) {
+	out, err := exec.ExecuteCommand(prober, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not probe %s: %s", filePath, strings.TrimSpace(err.Error()))
+	}
+
+	var ret Result
+	if err = json.Unmarshal([]byte(out), &ret); err != nil {
+		return nil, fmt.Errorf("Could not read what ffprobe found out about %s: %s", filePath, err.Error())
+	}
+
+	return &ret, nil
+}
+
+// IsContainer - Returns true if the file's container is the one of the format
+// (a file extension, e.g. mkv)
+func (r *Result) IsContainer(format string) bool {
+	name, ok := containerNames[strings.ToLower(format)]
+	if !ok {
+		name = strings.ToLower(format)
+	}
+
+	for _, formatName := range strings.Split(r.Format.FormatName, ",") {
+		if formatName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Codecs - Returns the codecs of the streams of the type (video, audio or
+// subtitle)
+func (r *Result) Codecs(codecType string) []string {
+	ret := make([]string, 0)
+
+	for _, stream := range r.Streams {
+		if stream.CodecType == codecType {
+			ret = append(ret, stream.CodecName)
+		}
+	}
+
+	return ret
+}
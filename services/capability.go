@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"torrentRenamer"
+	"torrentRenamer/config"
+)
+
+type Capability int
+
+const (
+	CapMovies Capability = 1 << iota
+	CapShows
+	CapAnime
+	CapAirDate
+	CapByID
+	CapCandidates
+	CapImages
+)
+
+var capabilityNames = []struct {
+	capability Capability
+	name       string
+}{
+	{CapMovies, "movies"},
+	{CapShows, "shows"},
+	{CapAnime, "anime"},
+	{CapAirDate, "airDate"},
+	{CapByID, "byId"},
+	{CapCandidates, "candidates"},
+	{CapImages, "images"},
+}
+
+// Has - Returns true if every one of the given capabilities is present
+func (c Capability) Has(required Capability) bool {
+	return c&required == required
+}
+
+// Names - Returns the names of the capabilities, as used in the config file
+func (c Capability) Names() []string {
+	names := make([]string, 0)
+
+	for _, entry := range capabilityNames {
+		if c.Has(entry.capability) {
+			names = append(names, entry.name)
+		}
+	}
+
+	return names
+}
+
+func (c Capability) String() string {
+	return strings.Join(c.Names(), ", ")
+}
+
+// ParseCapabilities - Combines capabilities given by their names, as used in
+// the config file
+func ParseCapabilities(names []string) (Capability, error) {
+	var ret Capability
+
+	for _, name := range names {
+		found := false
+
+		for _, entry := range capabilityNames {
+			if strings.EqualFold(entry.name, name) {
+				ret |= entry.capability
+				found = true
+			}
+		}
+
+		if !found {
+			return ret, fmt.Errorf("Unknown service capability %s", name)
+		}
+	}
+
+	return ret, nil
+}
+
+// RequiredCapabilities - Returns what a service has to support to look up the
+// given video
+func RequiredCapabilities(video torrentRenamer.Video) Capability {
+	var ret Capability
+
+	if video.IsMovie() {
+		ret |= CapMovies
+	} else {
+		ret |= CapShows
+	}
+
+	if show, ok := video.(*torrentRenamer.Show); ok {
+		if show.Anime {
+			ret |= CapAnime
+		}
+
+		if show.Aired != "" {
+			ret |= CapAirDate
+		}
+	}
+
+	if _, pinned := config.GetIdOverride(video.GetName()); pinned {
+		ret |= CapByID
+	}
+
+	return ret
+}
+
+// PreferredCapabilities - Returns what a service should support on top of the
+// required capabilities, e.g. images when artwork is downloaded
+func PreferredCapabilities() Capability {
+	var ret Capability
+
+	if config.GetConfig().MetadataFiles.Artwork {
+		ret |= CapImages
+	}
+
+	return ret
+}
+
+// supports - Returns true if the service is set up and has every one of the
+// capabilities
+func supports(service Service, capabilities Capability) bool {
+	return service.IsAvailable() && service.Capabilities().Has(capabilities)
+}
+
+// CanHandle - Returns true if the service is set up and supports everything
+// needed to look up the video
+func CanHandle(service Service, video torrentRenamer.Video) bool {
+	return supports(service, RequiredCapabilities(video))
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"net/url"
+	"time"
+	"torrentRenamer"
+	"torrentRenamer/fetch"
+)
+
+// RemoteService - Implemented by services that look videos up over HTTP
+type RemoteService interface {
+	BaseURL() string
+}
+
+type CheckResult struct {
+	Service   string
+	Available bool
+	Query     string
+	Result    string
+	Latency   time.Duration
+	Quota     map[string]string
+	Err       error
+}
+
+// getCannedVideo - Returns a well known video the service should be able to
+// find
+func getCannedVideo(service Service) torrentRenamer.Video {
+	if service.Capabilities().Has(CapMovies) {
+		return &torrentRenamer.Movie{Name: "The Matrix", Year: 1999, Ext: "mkv"}
+	}
+
+	return &torrentRenamer.Show{Name: "Breaking Bad", Season: 1, Episode: 1, Ext: "mkv"}
+}
+
+// CheckService - Looks up a well known video with the service to verify its
+// keys and connectivity
+func CheckService(service Service) CheckResult {
+	ret := CheckResult{
+		Service:   service.Name(),
+		Available: service.IsAvailable(),
+	}
+
+	if !ret.Available {
+		return ret
+	}
+
+	video := getCannedVideo(service)
+	ret.Query = video.GetNewName()
+
+	start := time.Now()
+	ret.Result, ret.Err = service.GetNewName(&video)
+	ret.Latency = time.Since(start)
+
+	if remote, ok := service.(RemoteService); ok {
+		baseURL, err := url.Parse(remote.BaseURL())
+		client, clientErr := fetch.GetClient()
+
+		if err == nil && clientErr == nil {
+			ret.Quota = client.GetQuota(baseURL.Host)
+		}
+	}
+
+	return ret
+}
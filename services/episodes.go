@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// Episode - An episode in a service's listing of a show. Services that only
+// know the year an episode aired leave Aired empty.
+type Episode struct {
+	Season  int       `json:"season"`
+	Episode int       `json:"episode"`
+	Title   string    `json:"title,omitempty"`
+	Aired   time.Time `json:"aired,omitempty"`
+	Year    int       `json:"year,omitempty"`
+	ImdbID  string    `json:"imdbId,omitempty"`
+}
+
+// HasAired - Returns true if the episode aired before now. Episodes without a
+// date have not been scheduled yet.
+func (e Episode) HasAired(now time.Time) bool {
+	if !e.Aired.IsZero() {
+		return !e.Aired.After(now)
+	}
+
+	return e.Year != 0 && e.Year <= now.Year()
+}
+
+// EpisodeListService - Implemented by services that can list every episode of
+// a show
+type EpisodeListService interface {
+	// ListEpisodes - Returns the show's name as the service knows it and its
+	// episodes, without specials. The show is looked up by its IMDb series ID,
+	// unless it is empty.
+	ListEpisodes(seriesID string, name string) (string, []Episode, error)
+}
+
+// GetEpisodeListService - Returns the default service if it can list episodes,
+// otherwise the first available service that can
+func GetEpisodeListService() (Service, EpisodeListService, error) {
+	if service := GetDefaultService(); service != nil && (*service).IsAvailable() {
+		if lister, ok := (*service).(EpisodeListService); ok {
+			return *service, lister, nil
+		}
+	}
+
+	for _, service := range GetRegistedServices() {
+		if lister, ok := service.(EpisodeListService); ok && service.IsAvailable() {
+			return service, lister, nil
+		}
+	}
+
+	return nil, nil, errors.New("No available service can list the episodes of a show")
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"torrentRenamer"
+	"torrentRenamer/config"
+)
+
+type IMDBService struct{}
+
+var (
+	imdbLoaded    *imdbIndex
+	imdbLoadErr   error
+	imdbIndexOnce sync.Once
+)
+
+func init() {
+	RegisterService(IMDBService{})
+}
+
+func (i *IMDBService) getIndex() (*imdbIndex, error) {
+	imdbIndexOnce.Do(func() {
+		imdbLoaded, imdbLoadErr = loadIMDBIndex(config.GetConfig().Services.Imdb.IndexPath)
+	})
+
+	return imdbLoaded, imdbLoadErr
+}
+
+func (i *IMDBService) searchMovie(idx *imdbIndex, m *torrentRenamer.Movie) (torrentRenamer.Movie, error) {
+	var ret torrentRenamer.Movie
+
+	if id, ok := config.GetIdOverride(m.Name); ok {
+		return i.movieByID(idx, id, m)
+	}
+
+	candidates := idx.findTitles(m.Name, func(t *imdbTitle) bool {
+		return t.IsMovie()
+	})
+
+	if m.Year != 0 {
+		// Release dates can differ between regions
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return yearDistance(candidates[a].Year, m.Year) < yearDistance(candidates[b].Year, m.Year)
+		})
+
+		if len(candidates) > 0 && yearDistance(candidates[0].Year, m.Year) > getYearTolerance() {
+			candidates = nil
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ret, fmt.Errorf("Could not find in IMDb index: %s", m.GetNewName())
+	}
+
+	return i.titleToMovie(idx, candidates[0], m), nil
+}
+
+func (i *IMDBService) movieByID(idx *imdbIndex, id string, m *torrentRenamer.Movie) (torrentRenamer.Movie, error) {
+	if title, ok := idx.Titles[id]; ok && title.IsMovie() {
+		return i.titleToMovie(idx, title, m), nil
+	}
+
+	return torrentRenamer.Movie{}, fmt.Errorf("Could not find movie %s in IMDb index", id)
+}
+
+func (i *IMDBService) titleToMovie(idx *imdbIndex, title *imdbTitle, m *torrentRenamer.Movie) torrentRenamer.Movie {
+	return torrentRenamer.Movie{
+		Name:     config.ApplyRenameOverrides(title.Title),
+		Year:     title.Year,
+		Ext:      m.Ext,
+		Metadata: i.titleToMetadata(idx, title.ID, title),
+	}
+}
+
+func (i *IMDBService) titleToMetadata(idx *imdbIndex, id string, title *imdbTitle) torrentRenamer.Metadata {
+	config := config.GetConfig()
+
+	metadata := torrentRenamer.Metadata{
+		OriginalName: title.OriginalTitle,
+		ImdbID:       id,
+		Genres:       title.Genres,
+		Runtime:      title.Runtime,
+	}
+
+	if config.Language != "" || config.Region != "" {
+		metadata.LocalizedName = idx.localizedTitle(title.ID, config.Language, config.Region)
+	}
+
+	return metadata
+}
+
+// findSeries - Returns the series with the given ID, or the series named like
+// the show if the ID is empty
+func (i *IMDBService) findSeries(idx *imdbIndex, seriesID string, name string) ([]*imdbTitle, error) {
+	var candidates []*imdbTitle
+
+	if seriesID != "" {
+		title, ok := idx.Titles[seriesID]
+		if !ok || !title.IsShow() {
+			return nil, fmt.Errorf("Could not find series %s in IMDb index", seriesID)
+		}
+
+		candidates = []*imdbTitle{title}
+	} else {
+		episodes, err := idx.getEpisodes()
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = idx.findTitles(name, func(t *imdbTitle) bool {
+			return t.IsShow()
+		})
+
+		// Remakes share their names, so prefer the series with the most episodes
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return len(episodes[candidates[a].ID]) > len(episodes[candidates[b].ID])
+		})
+	}
+
+	return candidates, nil
+}
+
+// searchShow - Looks up the episode of the series with the given ID, or of the
+// series named like the show if the ID is empty
+func (i *IMDBService) searchShow(idx *imdbIndex, seriesID string, s *torrentRenamer.Show) (torrentRenamer.Show, error) {
+	var ret torrentRenamer.Show
+
+	candidates, err := i.findSeries(idx, seriesID, s.Name)
+	if err != nil {
+		return ret, err
+	}
+
+	episodes, err := idx.getEpisodes()
+	if err != nil {
+		return ret, err
+	}
+
+	for _, series := range candidates {
+		episode, ok := episodes[series.ID][episodeKey(s.Season, s.Episode)]
+		if !ok {
+			continue
+		}
+
+		ret = torrentRenamer.Show{
+			Name:         config.ApplyRenameOverrides(series.Title),
+			Season:       s.Season,
+			Episode:      s.Episode,
+			Title:        episode.Title,
+			Ext:          s.Ext,
+			SeriesImdbID: series.ID,
+			Metadata:     i.titleToMetadata(idx, episode.ID, series),
+		}
+
+		return ret, nil
+	}
+
+	return ret, fmt.Errorf("Could not find in IMDb index: %s", s.GetNewName())
+}
+
+// ListEpisodes - Returns the episodes of the series with the given ID, or of
+// the series named like the show (or pinned to it) with the most episodes. IMDb
+// only knows the year they aired.
+func (i IMDBService) ListEpisodes(seriesID string, name string) (string, []Episode, error) {
+	idx, err := i.getIndex()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if seriesID == "" {
+		seriesID, _ = config.GetIdOverride(name)
+	}
+
+	candidates, err := i.findSeries(idx, seriesID, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(candidates) == 0 {
+		return "", nil, fmt.Errorf("Could not find %s in IMDb index", name)
+	}
+
+	episodes, err := idx.getEpisodes()
+	if err != nil {
+		return "", nil, err
+	}
+
+	series := candidates[0]
+	ret := make([]Episode, 0, len(episodes[series.ID]))
+
+	for _, episode := range episodes[series.ID] {
+		ret = append(ret, Episode{
+			Season:  episode.Season,
+			Episode: episode.Episode,
+			Title:   episode.Title,
+			Year:    episode.Year,
+			ImdbID:  episode.ID,
+		})
+	}
+
+	sort.Slice(ret, func(a, b int) bool {
+		if ret[a].Season != ret[b].Season {
+			return ret[a].Season < ret[b].Season
+		}
+
+		return ret[a].Episode < ret[b].Episode
+	})
+
+	return config.ApplyRenameOverrides(series.Title), ret, nil
+}
+
+func yearDistance(a int, b int) int {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
+
+func (i IMDBService) Name() string {
+	return "IMDB"
+}
+
+func (i IMDBService) Capabilities() Capability {
+	return CapMovies | CapShows | CapByID | CapCandidates
+}
+
+func (i IMDBService) Search(v *torrentRenamer.Video) (torrentRenamer.Video, error) {
+	video := *v
+
+	idx, err := i.getIndex()
+	if err != nil {
+		return &torrentRenamer.Show{}, err
+	}
+
+	if movie, ok := video.(*torrentRenamer.Movie); ok {
+		movie, err := i.searchMovie(idx, movie)
+		return &movie, err
+	}
+
+	if show, ok := video.(*torrentRenamer.Show); ok {
+		id, _ := config.GetIdOverride(show.Name)
+		show, err := i.searchShow(idx, id, show)
+		return &show, err
+	}
+
+	return &torrentRenamer.Show{}, nil
+}
+
+func (i IMDBService) LookupByID(id string, v *torrentRenamer.Video) (torrentRenamer.Video, error) {
+	video := *v
+
+	idx, err := i.getIndex()
+	if err != nil {
+		return &torrentRenamer.Show{}, err
+	}
+
+	if movie, ok := video.(*torrentRenamer.Movie); ok {
+		movie, err := i.movieByID(idx, id, movie)
+		return &movie, err
+	}
+
+	if show, ok := video.(*torrentRenamer.Show); ok {
+		show, err := i.searchShow(idx, id, show)
+		return &show, err
+	}
+
+	return &torrentRenamer.Show{}, nil
+}
+
+func (i IMDBService) IsAvailable() bool {
+	_, err := os.Stat(config.GetConfig().Services.Imdb.IndexPath)
+
+	return err == nil
+}
+
+func (i IMDBService) GetNewName(video *torrentRenamer.Video) (string, error) {
+	result, err := i.Search(video)
+	if err != nil {
+		return "", err
+	}
+
+	movieTemplate, showTemplate := i.GetRenameTemplates()
+
+	return getNewNameFromResult(result, movieTemplate, showTemplate)
+}
+
+func (i IMDBService) GetRenameTemplates() (string, string) {
+	templates := config.GetConfig().Services.Imdb.RenameTemplates
+
+	return templates.Movies, templates.Shows
+}
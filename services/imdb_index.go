@@ -0,0 +1,438 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"torrentRenamer/config"
+	"torrentRenamer/logger"
+	"torrentRenamer/match"
+)
+
+const (
+	imdbIndexVersion = 5
+	imdbNull         = `\N`
+
+	imdbEpisodesSuffix = ".episodes"
+	imdbAkasSuffix     = ".akas"
+)
+
+// imdbEnglishRegions - Alternate titles from these regions are searchable even
+// without a language or region set, as most releases are named after them
+var imdbEnglishRegions = map[string]bool{
+	"US":  true,
+	"GB":  true,
+	"XWW": true,
+}
+
+type imdbTitle struct {
+	ID            string
+	Type          string
+	Title         string
+	OriginalTitle string
+	Year          int
+	Genres        []string
+	Runtime       int
+}
+
+type imdbEpisode struct {
+	ID      string
+	Title   string
+	Year    int
+	Season  int
+	Episode int
+}
+
+// imdbEpisodeNumber - Where an episode belongs, read from title.episode before
+// the episodes' titles
+type imdbEpisodeNumber struct {
+	SeriesID string
+	Season   int
+	Episode  int
+}
+
+type imdbAka struct {
+	Title    string
+	Region   string
+	Language string
+	Display  bool
+}
+
+// imdbIndex - The titles and names of the index are loaded on every run. The
+// episodes and alternate titles are stored in files of their own next to it,
+// and only loaded once a show or a localized title is looked up.
+type imdbIndex struct {
+	Version int
+	Titles  map[string]*imdbTitle
+	Names   map[string][]string
+
+	path         string
+	episodes     map[string]map[string]imdbEpisode
+	episodesErr  error
+	episodesOnce sync.Once
+	akas         map[string][]imdbAka
+	akasOnce     sync.Once
+}
+
+func (t *imdbTitle) IsMovie() bool {
+	return t.Type == "movie" || t.Type == "tvMovie"
+}
+
+func (t *imdbTitle) IsShow() bool {
+	return t.Type == "tvSeries" || t.Type == "tvMiniSeries"
+}
+
+func episodeKey(season int, episode int) string {
+	return fmt.Sprintf("%d:%d", season, episode)
+}
+
+func (idx *imdbIndex) addName(name string, id string) {
+	key := match.Normalize(name)
+	if key == "" {
+		return
+	}
+
+	for _, existing := range idx.Names[key] {
+		if existing == id {
+			return
+		}
+	}
+
+	idx.Names[key] = append(idx.Names[key], id)
+}
+
+// getEpisodes - Returns the episodes by series ID and episode key
+func (idx *imdbIndex) getEpisodes() (map[string]map[string]imdbEpisode, error) {
+	idx.episodesOnce.Do(func() {
+		idx.episodesErr = loadGob(idx.path+imdbEpisodesSuffix, &idx.episodes)
+	})
+
+	return idx.episodes, idx.episodesErr
+}
+
+// getAkas - Returns the alternate titles in the language or region the index
+// was built for, by title ID. They are only needed for localized titles, so
+// without them titles are simply not localized.
+func (idx *imdbIndex) getAkas() map[string][]imdbAka {
+	idx.akasOnce.Do(func() {
+		if err := loadGob(idx.path+imdbAkasSuffix, &idx.akas); err != nil {
+			logger.Warnf("Could not load the alternate titles of the IMDb index: %s", err.Error())
+		}
+	})
+
+	return idx.akas
+}
+
+func (idx *imdbIndex) findTitles(name string, matches func(*imdbTitle) bool) []*imdbTitle {
+	ret := make([]*imdbTitle, 0)
+
+	for _, id := range idx.Names[match.Normalize(name)] {
+		if title, ok := idx.Titles[id]; ok && matches(title) {
+			ret = append(ret, title)
+		}
+	}
+
+	return ret
+}
+
+// localizedTitle - Returns the alternate title that best matches the language
+// and region, preferring titles that IMDb itself displays in that region
+func (idx *imdbIndex) localizedTitle(id string, language string, region string) string {
+	var ret string
+	best := 0
+
+	for _, aka := range idx.getAkas()[id] {
+		score := 0
+
+		if region != "" && strings.EqualFold(aka.Region, region) {
+			score += 4
+		}
+
+		if language != "" && strings.EqualFold(aka.Language, language) {
+			score += 2
+		}
+
+		if score == 0 {
+			continue
+		}
+
+		if aka.Display {
+			score++
+		}
+
+		if score > best {
+			ret, best = aka.Title, score
+		}
+	}
+
+	return ret
+}
+
+// openDataset - Opens <dir>/<name>.tsv.gz, falling back to the uncompressed
+// <dir>/<name>.tsv
+func openDataset(dir string, name string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(dir, name+".tsv.gz"))
+	if err == nil {
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return struct {
+			io.Reader
+			io.Closer
+		}{reader, file}, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return os.Open(filepath.Join(dir, name+".tsv"))
+}
+
+// readDataset - Calls fn with the columns of every row of the dataset, skipping
+// the header
+func readDataset(dir string, name string, fn func([]string)) error {
+	reader, err := openDataset(dir, name)
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+
+		fn(strings.Split(scanner.Text(), "\t"))
+	}
+
+	return scanner.Err()
+}
+
+func parseDatasetInt(value string) int {
+	if value == imdbNull {
+		return 0
+	}
+
+	ret, _ := strconv.Atoi(value)
+
+	return ret
+}
+
+// isLocalAka - Returns true if the alternate title is in the language or
+// region localized titles are looked up for
+func isLocalAka(aka imdbAka, language string, region string) bool {
+	return (region != "" && strings.EqualFold(aka.Region, region)) || (language != "" && strings.EqualFold(aka.Language, language))
+}
+
+// BuildIMDBIndex - Reads the title.episode, title.basics and title.akas dumps
+// from the given directory and writes the index used by the IMDB service.
+// Only the alternate titles in the configured language or region are kept, so
+// the index has to be built again after changing them.
+func BuildIMDBIndex(datasetDir string, indexPath string) error {
+	conf := config.GetConfig()
+
+	idx := &imdbIndex{
+		Version: imdbIndexVersion,
+		Titles:  make(map[string]*imdbTitle),
+		Names:   make(map[string][]string),
+	}
+
+	episodes := make(map[string]map[string]imdbEpisode)
+	akas := make(map[string][]imdbAka)
+
+	// Episodes are numbered in title.episode and named in title.basics, so
+	// only the numbered ones are kept while reading the latter
+	numbers := make(map[string]imdbEpisodeNumber)
+
+	err := readDataset(datasetDir, "title.episode", func(row []string) {
+		if len(row) < 4 {
+			return
+		}
+
+		season, episode := parseDatasetInt(row[2]), parseDatasetInt(row[3])
+		if season == 0 || episode == 0 {
+			return
+		}
+
+		numbers[row[0]] = imdbEpisodeNumber{SeriesID: row[1], Season: season, Episode: episode}
+	})
+	if err != nil {
+		return fmt.Errorf("Could not read title.episode: %s", err.Error())
+	}
+
+	err = readDataset(datasetDir, "title.basics", func(row []string) {
+		if len(row) < 9 {
+			return
+		}
+
+		if row[1] == "tvEpisode" {
+			number, ok := numbers[row[0]]
+			if !ok {
+				return
+			}
+
+			delete(numbers, row[0])
+
+			if _, ok := episodes[number.SeriesID]; !ok {
+				episodes[number.SeriesID] = make(map[string]imdbEpisode)
+			}
+
+			episodes[number.SeriesID][episodeKey(number.Season, number.Episode)] = imdbEpisode{
+				ID:      row[0],
+				Title:   row[2],
+				Year:    parseDatasetInt(row[5]),
+				Season:  number.Season,
+				Episode: number.Episode,
+			}
+
+			return
+		}
+
+		title := &imdbTitle{
+			ID:            row[0],
+			Type:          row[1],
+			Title:         row[2],
+			OriginalTitle: row[3],
+			Year:          parseDatasetInt(row[5]),
+			Runtime:       parseDatasetInt(row[7]),
+		}
+
+		if !title.IsMovie() && !title.IsShow() {
+			return
+		}
+
+		if row[8] != imdbNull {
+			title.Genres = strings.Split(row[8], ",")
+		}
+
+		idx.Titles[title.ID] = title
+		idx.addName(title.Title, title.ID)
+		idx.addName(title.OriginalTitle, title.ID)
+	})
+	if err != nil {
+		return fmt.Errorf("Could not read title.basics: %s", err.Error())
+	}
+
+	numbers = nil
+
+	// Episodes of e.g. TV specials, which are not indexed
+	for seriesID := range episodes {
+		if title, ok := idx.Titles[seriesID]; !ok || !title.IsShow() {
+			delete(episodes, seriesID)
+		}
+	}
+
+	err = readDataset(datasetDir, "title.akas", func(row []string) {
+		if len(row) < 6 {
+			return
+		}
+
+		if _, ok := idx.Titles[row[0]]; !ok {
+			return
+		}
+
+		aka := imdbAka{
+			Title:    row[2],
+			Region:   strings.Replace(row[3], imdbNull, "", 1),
+			Language: strings.Replace(row[4], imdbNull, "", 1),
+			Display:  strings.Contains(row[5], "imdbDisplay"),
+		}
+
+		local := isLocalAka(aka, conf.Language, conf.Region)
+
+		if local || imdbEnglishRegions[aka.Region] {
+			idx.addName(aka.Title, row[0])
+		}
+
+		if local {
+			akas[row[0]] = append(akas[row[0]], aka)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("Could not read title.akas: %s", err.Error())
+	}
+
+	// The index itself is written last, so it is never newer than its parts
+	if err = saveGob(indexPath+imdbEpisodesSuffix, episodes); err != nil {
+		return err
+	}
+
+	if err = saveGob(indexPath+imdbAkasSuffix, akas); err != nil {
+		return err
+	}
+
+	return saveGob(indexPath, idx)
+}
+
+// saveGob - Writes the value to the path atomically
+func saveGob(path string, value interface{}) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	err = gob.NewEncoder(writer).Encode(value)
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func loadGob(path string, target interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return gob.NewDecoder(bufio.NewReader(file)).Decode(target)
+}
+
+func loadIMDBIndex(indexPath string) (*imdbIndex, error) {
+	idx := &imdbIndex{path: indexPath}
+	if err := loadGob(indexPath, idx); err != nil {
+		return nil, err
+	}
+
+	if idx.Version != imdbIndexVersion {
+		return nil, fmt.Errorf("IMDb index at %s is outdated, please run import-imdb again", indexPath)
+	}
+
+	return idx, nil
+}
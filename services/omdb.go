@@ -6,14 +6,16 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 	"torrentRenamer"
 	"torrentRenamer/config"
 	"torrentRenamer/fetch"
-	"torrentRenamer/util"
+	"torrentRenamer/logger"
+	"torrentRenamer/match"
 )
 
 const (
-	apiURL = "http://www.omdbapi.com/"
+	defaultOMDBURL = "https://www.omdbapi.com/"
 )
 
 type omdbResponse struct {
@@ -22,7 +24,29 @@ type omdbResponse struct {
 	Season   string `json:"Season"`
 	Episode  string `json:"Episode"`
 	Response string `json:"Response"`
+	Error    string `json:"Error"`
 	SeriesID string `json:"seriesID"`
+	ImdbID   string `json:"imdbID"`
+	Genre    string `json:"Genre"`
+	Rated    string `json:"Rated"`
+	Runtime  string `json:"Runtime"`
+	Director string `json:"Director"`
+	Plot     string `json:"Plot"`
+	Poster   string `json:"Poster"`
+	Language string `json:"Language"`
+	Country  string `json:"Country"`
+}
+
+type omdbSearchResult struct {
+	Title  string `json:"Title"`
+	Year   string `json:"Year"`
+	ImdbID string `json:"imdbID"`
+}
+
+type omdbSearchResponse struct {
+	Search   []omdbSearchResult `json:"Search"`
+	Response string             `json:"Response"`
+	Error    string             `json:"Error"`
 }
 
 type OMDBService struct{}
@@ -31,25 +55,71 @@ func init() {
 	RegisterService(OMDBService{})
 }
 
-func (o *OMDBService) getOMDBResponse(query *url.Values) (omdbResponse, error) {
-	var ret omdbResponse
+func (o *OMDBService) fetchOMDB(query *url.Values, target interface{}) error {
 	requestURL := o.getURLWithQuery(query)
 
 	bytes, err := fetch.Get(requestURL)
 	if err != nil {
-		return ret, err
+		if statusErr, ok := err.(*fetch.StatusError); ok {
+			var res omdbResponse
+			if json.Unmarshal(statusErr.Body, &res) == nil && res.Error != "" {
+				return fmt.Errorf("%s: %s", statusErr.Error(), res.Error)
+			}
+		}
+
+		return err
 	}
 
-	err = json.Unmarshal(bytes, &ret)
+	return json.Unmarshal(bytes, target)
+}
+
+func (o *OMDBService) getOMDBResponse(query *url.Values) (omdbResponse, error) {
+	var ret omdbResponse
+	err := o.fetchOMDB(query, &ret)
 
 	return ret, err
 }
 
+// omdbValue - OMDB uses "N/A" for every field it has no value for
+func omdbValue(value string) string {
+	if value == "N/A" {
+		return ""
+	}
+
+	return value
+}
+
+func omdbList(value string) []string {
+	value = omdbValue(value)
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ", ")
+}
+
+func (o *OMDBService) responseToMetadata(r *omdbResponse) torrentRenamer.Metadata {
+	runtime, _ := strconv.Atoi(strings.TrimSuffix(omdbValue(r.Runtime), " min"))
+
+	return torrentRenamer.Metadata{
+		ImdbID:   omdbValue(r.ImdbID),
+		Genres:   omdbList(r.Genre),
+		Rated:    omdbValue(r.Rated),
+		Runtime:  runtime,
+		Director: omdbValue(r.Director),
+		Plot:     omdbValue(r.Plot),
+		Poster:   omdbValue(r.Poster),
+		Language: omdbValue(r.Language),
+		Country:  omdbValue(r.Country),
+	}
+}
+
 func (o *OMDBService) responseToMovie(r *omdbResponse) torrentRenamer.Movie {
-	year, _ := strconv.Atoi(r.Year)
+	year := parseOMDBYear(r.Year)
 	return torrentRenamer.Movie{
-		Name: config.ApplyRenameOverrides(r.Title),
-		Year: year,
+		Name:     config.ApplyRenameOverrides(r.Title),
+		Year:     year,
+		Metadata: o.responseToMetadata(r),
 	}
 }
 
@@ -57,9 +127,11 @@ func (o *OMDBService) responseToShow(r *omdbResponse) torrentRenamer.Show {
 	season, _ := strconv.Atoi(r.Season)
 	episode, _ := strconv.Atoi((r.Episode))
 	return torrentRenamer.Show{
-		Title:   r.Title,
-		Season:  season,
-		Episode: episode,
+		Title:        r.Title,
+		Season:       season,
+		Episode:      episode,
+		SeriesImdbID: omdbValue(r.SeriesID),
+		Metadata:     o.responseToMetadata(r),
 	}
 }
 
@@ -74,19 +146,90 @@ func (o *OMDBService) getCommonQuery() url.Values {
 }
 
 func (o *OMDBService) getURLWithQuery(q *url.Values) string {
-	return fmt.Sprintf("%s?%s", apiURL, q.Encode())
+	return fmt.Sprintf("%s?%s", o.BaseURL(), q.Encode())
 }
 
-func (o *OMDBService) searchMovie(m *torrentRenamer.Movie) (torrentRenamer.Movie, error) {
-	var ret torrentRenamer.Movie
+// searchMovies - Searches OMDB for movies with a similar title, released in the
+// year if it is not 0
+func (o *OMDBService) searchMovies(name string, year int) (omdbSearchResponse, error) {
+	var res omdbSearchResponse
+
 	query := o.getCommonQuery()
 	query.Add("type", "movie")
-	query.Add("t", m.Name)
+	query.Add("s", name)
 
-	if m.Year != 0 {
-		query.Add("y", string(m.Year))
+	if year != 0 {
+		query.Add("y", strconv.Itoa(year))
 	}
 
+	err := o.fetchOMDB(&query, &res)
+
+	return res, err
+}
+
+// findMovieID - Searches OMDB for movies with a similar title and returns the
+// IMDb ID of the best match. Release dates can differ between regions, so if
+// nothing was released in the movie's year, the other years are searched too.
+func (o *OMDBService) findMovieID(m *torrentRenamer.Movie) (string, error) {
+	res, err := o.searchMovies(m.Name, m.Year)
+	if err == nil && res.Response != "True" && m.Year != 0 {
+		res, err = o.searchMovies(m.Name, 0)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if res.Response != "True" {
+		return "", fmt.Errorf("Could not find in OMDB: %s", m.GetNewName())
+	}
+
+	candidates := make([]match.Candidate, len(res.Search))
+	for i, result := range res.Search {
+		candidates[i] = match.Candidate{Title: result.Title, Year: parseOMDBYear(result.Year)}
+	}
+
+	best, err := bestMatch(m.Name, m.Year, candidates)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Search[best].ImdbID, nil
+}
+
+// parseOMDBYear - Years of series are ranges like "2005–2013"
+func parseOMDBYear(year string) int {
+	if len(year) > 4 {
+		year = year[:4]
+	}
+
+	ret, _ := strconv.Atoi(year)
+
+	return ret
+}
+
+func (o *OMDBService) searchMovie(m *torrentRenamer.Movie) (torrentRenamer.Movie, error) {
+	var ret torrentRenamer.Movie
+
+	id, ok := config.GetIdOverride(m.Name)
+	if !ok {
+		var err error
+
+		if id, err = o.findMovieID(m); err != nil {
+			return ret, err
+		}
+	}
+
+	return o.movieByID(id, m)
+}
+
+func (o *OMDBService) movieByID(id string, m *torrentRenamer.Movie) (torrentRenamer.Movie, error) {
+	var ret torrentRenamer.Movie
+
+	query := o.getCommonQuery()
+	query.Add("type", "movie")
+	query.Add("i", id)
+
 	res, err := o.getOMDBResponse(&query)
 	if err == nil {
 		if res.Response != "True" {
@@ -100,26 +243,78 @@ func (o *OMDBService) searchMovie(m *torrentRenamer.Movie) (torrentRenamer.Movie
 	return ret, err
 }
 
-func (o *OMDBService) searchShowNameFromID(showID string) string {
-	var title string
-
+// searchSeries - Looks up the series with the given ID, or the series with the
+// name if the ID is empty
+func (o *OMDBService) searchSeries(seriesID string, name string) (omdbResponse, error) {
 	query := o.getCommonQuery()
-	query.Add("i", showID)
 	query.Add("type", "series")
 
+	if seriesID != "" {
+		query.Add("i", seriesID)
+	} else {
+		query.Add("t", name)
+	}
+
 	res, err := o.getOMDBResponse(&query)
+	if err == nil && res.Response != "True" {
+		err = fmt.Errorf("Could not find the series %s in OMDB: %s", name, res.Error)
+	}
+
+	return res, err
+}
+
+// searchSeriesFromID - Returns the series' name and poster
+func (o *OMDBService) searchSeriesFromID(showID string) (string, string) {
+	var title, poster string
+
+	res, err := o.searchSeries(showID, showID)
 	if err == nil {
 		title = res.Title
+		poster = omdbValue(res.Poster)
+	} else {
+		logger.Warnf("Could not look up the series %s in OMDB: %s", showID, err.Error())
+	}
+
+	return config.ApplyRenameOverrides(title), poster
+}
+
+// searchShow - Looks up the episode of the series with the given ID, or by the
+// show's name if the ID is empty. The listing of the batch's season is used if
+// it was fetched for the same series, and episodes named by their air date are
+// first found in the listing of every season.
+func (o *OMDBService) searchShow(seriesID string, s *torrentRenamer.Show) (torrentRenamer.Show, error) {
+	if s.Aired != "" && s.Season == 0 {
+		aired, err := o.episodeByAirDate(seriesID, s)
+		if err != nil {
+			return torrentRenamer.Show{}, err
+		}
+
+		s = &aired
 	}
 
-	return config.ApplyRenameOverrides(title)
+	if pinnedID, _ := config.GetIdOverride(s.Name); pinnedID == seriesID {
+		if season, ok := getCachedOMDBSeason(s.Name, s.Season); ok {
+			return o.showFromSeason(season, s)
+		}
+	}
+
+	return o.searchEpisode(seriesID, s)
 }
 
-func (o *OMDBService) searchShow(s *torrentRenamer.Show) (torrentRenamer.Show, error) {
+// searchEpisode - Looks up the episode of the series with the given ID, or by
+// the show's name if the ID is empty
+func (o *OMDBService) searchEpisode(seriesID string, s *torrentRenamer.Show) (torrentRenamer.Show, error) {
 	var ret torrentRenamer.Show
+
 	query := o.getCommonQuery()
 	query.Add("type", "episode")
-	query.Add("t", s.Name)
+
+	if seriesID != "" {
+		query.Add("i", seriesID)
+	} else {
+		query.Add("t", s.Name)
+	}
+
 	query.Add("Season", fmt.Sprintf("%d", s.Season))
 	query.Add("Episode", fmt.Sprintf("%d", s.Episode))
 
@@ -133,9 +328,15 @@ func (o *OMDBService) searchShow(s *torrentRenamer.Show) (torrentRenamer.Show, e
 	}
 
 	ret = o.responseToShow(&res)
-	ret.Name = o.searchShowNameFromID(res.SeriesID)
+	ret.Name, ret.SeriesPoster = o.searchSeriesFromID(res.SeriesID)
 	ret.Ext = s.Ext
 
+	if seriesID == "" {
+		if _, err = bestMatch(s.Name, 0, []match.Candidate{{Title: ret.Name}}); err != nil {
+			return torrentRenamer.Show{}, err
+		}
+	}
+
 	return ret, err
 }
 
@@ -143,6 +344,10 @@ func (o OMDBService) Name() string {
 	return "OMDB"
 }
 
+func (o OMDBService) Capabilities() Capability {
+	return CapMovies | CapShows | CapAirDate | CapByID | CapCandidates | CapImages
+}
+
 func (o OMDBService) Search(v *torrentRenamer.Video) (torrentRenamer.Video, error) {
 	video := *v
 
@@ -156,13 +361,39 @@ func (o OMDBService) Search(v *torrentRenamer.Video) (torrentRenamer.Video, erro
 
 	show, ok := video.(*torrentRenamer.Show)
 	if ok {
-		show, err := o.searchShow(show)
+		id, _ := config.GetIdOverride(show.Name)
+		show, err := o.searchShow(id, show)
+		return &show, err
+	}
+
+	return &torrentRenamer.Show{}, nil
+}
+
+func (o OMDBService) LookupByID(id string, v *torrentRenamer.Video) (torrentRenamer.Video, error) {
+	video := *v
+
+	if movie, ok := video.(*torrentRenamer.Movie); ok {
+		movie, err := o.movieByID(id, movie)
+		return &movie, err
+	}
+
+	if show, ok := video.(*torrentRenamer.Show); ok {
+		show, err := o.searchShow(id, show)
 		return &show, err
 	}
 
 	return &torrentRenamer.Show{}, nil
 }
 
+func (o OMDBService) BaseURL() string {
+	baseURL := config.GetConfig().Services.Omdb.BaseUrl
+	if baseURL == "" {
+		return defaultOMDBURL
+	}
+
+	return baseURL
+}
+
 func (o OMDBService) IsAvailable() bool {
 	config := config.GetConfig()
 
@@ -175,19 +406,13 @@ func (o OMDBService) GetNewName(video *torrentRenamer.Video) (string, error) {
 		return "", err
 	}
 
-	config := config.GetConfig()
-
-	if !result.IsValid() {
-		return "", fmt.Errorf("could not find valid video results")
-	}
+	movieTemplate, showTemplate := o.GetRenameTemplates()
 
-	if result.IsMovie() {
-		return util.InsertTemplateData(config.Services.Omdb.RenameTemplates.Movies, result)
-	}
-
-	return util.InsertTemplateData(config.Services.Omdb.RenameTemplates.Shows, result)
+	return getNewNameFromResult(result, movieTemplate, showTemplate)
 }
 
-func (o OMDBService) GetServiceName() string {
-	return "OMDB"
+func (o OMDBService) GetRenameTemplates() (string, string) {
+	templates := config.GetConfig().Services.Omdb.RenameTemplates
+
+	return templates.Movies, templates.Shows
 }
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/logger"
+	"torrentRenamer/match"
+)
+
+type omdbSeasonEpisode struct {
+	Title    string `json:"Title"`
+	Released string `json:"Released"`
+	Episode  string `json:"Episode"`
+	ImdbID   string `json:"imdbID"`
+}
+
+type omdbSeasonResponse struct {
+	Title        string              `json:"Title"`
+	Season       string              `json:"Season"`
+	TotalSeasons string              `json:"totalSeasons"`
+	Episodes     []omdbSeasonEpisode `json:"Episodes"`
+	Response     string              `json:"Response"`
+	Error        string              `json:"Error"`
+
+	// Series - The series the season belongs to, which the listing only names
+	Series *omdbResponse `json:"-"`
+}
+
+var (
+	omdbSeasons    = make(map[string]*omdbSeasonResponse)
+	omdbSeasonsMux sync.RWMutex
+)
+
+func omdbSeasonKey(name string, season int) string {
+	return fmt.Sprintf("%s:%d", strings.ToLower(name), season)
+}
+
+func getCachedOMDBSeason(name string, season int) (*omdbSeasonResponse, bool) {
+	omdbSeasonsMux.RLock()
+	defer omdbSeasonsMux.RUnlock()
+
+	res, ok := omdbSeasons[omdbSeasonKey(name, season)]
+
+	return res, ok
+}
+
+// searchSeason - Fetches the listing of the season of the series with the given
+// ID, or of the show pinned to or named like name if the ID is empty
+func (o *OMDBService) searchSeason(seriesID string, name string, season int) (*omdbSeasonResponse, error) {
+	var ret omdbSeasonResponse
+
+	query := o.getCommonQuery()
+
+	if seriesID == "" {
+		seriesID, _ = config.GetIdOverride(name)
+	}
+
+	if seriesID != "" {
+		query.Add("i", seriesID)
+	} else {
+		query.Add("t", name)
+	}
+
+	query.Add("Season", strconv.Itoa(season))
+
+	if err := o.fetchOMDB(&query, &ret); err != nil {
+		return nil, err
+	}
+
+	if ret.Response != "True" {
+		return nil, fmt.Errorf("Could not find season %d of %s in OMDB: %s", season, name, ret.Error)
+	}
+
+	return &ret, nil
+}
+
+func (o *OMDBService) showFromSeason(season *omdbSeasonResponse, s *torrentRenamer.Show) (torrentRenamer.Show, error) {
+	var ret torrentRenamer.Show
+
+	if _, pinned := config.GetIdOverride(s.Name); !pinned {
+		if _, err := bestMatch(s.Name, 0, []match.Candidate{{Title: season.Title}}); err != nil {
+			return ret, err
+		}
+	}
+
+	for _, episode := range season.Episodes {
+		if number, _ := strconv.Atoi(episode.Episode); number == s.Episode {
+			ret = torrentRenamer.Show{
+				Name:    config.ApplyRenameOverrides(season.Title),
+				Season:  s.Season,
+				Episode: s.Episode,
+				Title:   episode.Title,
+				Ext:     s.Ext,
+			}
+
+			// The listing only has the episodes' titles and IDs. Only what
+			// holds for every episode is taken from the series, the plot,
+			// director, runtime and still are the episode's own.
+			if season.Series != nil {
+				series := o.responseToMetadata(season.Series)
+
+				ret.SeriesImdbID = series.ImdbID
+				ret.SeriesPoster = series.Poster
+				ret.Genres = series.Genres
+				ret.Rated = series.Rated
+				ret.Language = series.Language
+				ret.Country = series.Country
+			}
+
+			ret.ImdbID = omdbValue(episode.ImdbID)
+
+			return ret, nil
+		}
+	}
+
+	return ret, &EpisodeNotFoundError{Name: season.Title, Season: s.Season, Episode: s.Episode}
+}
+
+// PrepareBatch - Fetches the full listing of every season that more than one
+// episode of the batch belongs to, so those episodes are resolved from a
+// single request.
+func (o OMDBService) PrepareBatch(videos []torrentRenamer.Video) {
+	counts := make(map[string]int)
+	shows := make(map[string]*torrentRenamer.Show)
+
+	for _, video := range videos {
+		if show, ok := video.(*torrentRenamer.Show); ok && show.Aired == "" {
+			key := omdbSeasonKey(show.Name, show.Season)
+			counts[key]++
+			shows[key] = show
+		}
+	}
+
+	for key, count := range counts {
+		if count < 2 {
+			continue
+		}
+
+		if _, ok := getCachedOMDBSeason(shows[key].Name, shows[key].Season); ok {
+			continue
+		}
+
+		season, err := o.searchSeason("", shows[key].Name, shows[key].Season)
+		if err != nil {
+			logger.Debugf("Could not prefetch season %d of %s, looking its episodes up one by one: %s", shows[key].Season, shows[key].Name, err.Error())
+			continue
+		}
+
+		seriesID, _ := config.GetIdOverride(shows[key].Name)
+
+		series, err := o.searchSeries(seriesID, season.Title)
+		if err == nil {
+			season.Series = &series
+		} else {
+			logger.Warnf("Could not look up the series of season %d of %s, its episodes will lack its details: %s", shows[key].Season, shows[key].Name, err.Error())
+		}
+
+		omdbSeasonsMux.Lock()
+		omdbSeasons[key] = season
+		omdbSeasonsMux.Unlock()
+	}
+}
+
+func omdbEpisodes(season *omdbSeasonResponse, number int) []Episode {
+	ret := make([]Episode, 0, len(season.Episodes))
+
+	for _, episode := range season.Episodes {
+		aired, _ := time.Parse("2006-01-02", episode.Released)
+		episodeNumber, _ := strconv.Atoi(episode.Episode)
+
+		ret = append(ret, Episode{
+			Season:  number,
+			Episode: episodeNumber,
+			Title:   omdbValue(episode.Title),
+			Aired:   aired,
+			ImdbID:  omdbValue(episode.ImdbID),
+		})
+	}
+
+	return ret
+}
+
+// episodeByAirDate - Returns the show with the season and episode of the
+// episode that aired on its air date
+func (o *OMDBService) episodeByAirDate(seriesID string, s *torrentRenamer.Show) (torrentRenamer.Show, error) {
+	ret := *s
+
+	_, episodes, err := o.ListEpisodes(seriesID, s.Name)
+	if err != nil {
+		return ret, err
+	}
+
+	for _, episode := range episodes {
+		if !episode.Aired.IsZero() && episode.Aired.Format("2006-01-02") == s.Aired {
+			ret.Season, ret.Episode = episode.Season, episode.Episode
+
+			return ret, nil
+		}
+	}
+
+	return ret, fmt.Errorf("%s has no episode that aired on %s", s.Name, s.Aired)
+}
+
+// ListEpisodes - Fetches the listing of every season of the show, starting with
+// the first one, which tells how many seasons there are
+func (o OMDBService) ListEpisodes(seriesID string, name string) (string, []Episode, error) {
+	first, err := o.searchSeason(seriesID, name, 1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if _, pinned := config.GetIdOverride(name); !pinned && seriesID == "" {
+		if _, err = bestMatch(name, 0, []match.Candidate{{Title: first.Title}}); err != nil {
+			return "", nil, err
+		}
+	}
+
+	ret := omdbEpisodes(first, 1)
+
+	seasons, err := strconv.Atoi(first.TotalSeasons)
+	if err != nil {
+		seasons = 1
+	}
+
+	for number := 2; number <= seasons; number++ {
+		season, err := o.searchSeason(seriesID, name, number)
+		if err != nil {
+			return "", nil, err
+		}
+
+		ret = append(ret, omdbEpisodes(season, number)...)
+	}
+
+	return config.ApplyRenameOverrides(first.Title), ret, nil
+}
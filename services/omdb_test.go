@@ -0,0 +1,113 @@
+package services
+
+import (
+	"testing"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/fetch"
+)
+
+// replayOMDB - Answers OMDB requests from the fixtures in testdata
+func replayOMDB(t *testing.T) {
+	client, err := fetch.NewClient(fetch.ClientOptions{
+		FixtureMode: fetch.ModeReplay,
+		FixtureDir:  "testdata/fixtures",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch.SetClient(client)
+
+	omdb := &config.GetConfig().Services.Omdb
+	omdb.ApiKey = "any key"
+	omdb.BaseUrl = ""
+}
+
+func TestOMDBSearchMovie(t *testing.T) {
+	replayOMDB(t)
+
+	tests := []struct {
+		name   string
+		movie  torrentRenamer.Movie
+		want   torrentRenamer.Movie
+		hasErr bool
+	}{
+		{
+			name:  "year",
+			movie: torrentRenamer.Movie{Name: "The Matrix", Year: 1999, Ext: "mkv"},
+			want:  torrentRenamer.Movie{Name: "The Matrix", Year: 1999, Ext: "mkv"},
+		},
+		{
+			name:  "other year falls back to any year",
+			movie: torrentRenamer.Movie{Name: "The Matrix", Year: 2000, Ext: "mp4"},
+			want:  torrentRenamer.Movie{Name: "The Matrix", Year: 1999, Ext: "mp4"},
+		},
+		{
+			name:  "no year picks the closest title",
+			movie: torrentRenamer.Movie{Name: "The Matrix", Ext: "avi"},
+			want:  torrentRenamer.Movie{Name: "The Matrix", Year: 1999, Ext: "avi"},
+		},
+		{
+			name:   "not found",
+			movie:  torrentRenamer.Movie{Name: "Not A Real Movie", Year: 2001, Ext: "mkv"},
+			hasErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var video torrentRenamer.Video = &test.movie
+
+			result, err := OMDBService{}.Search(&video)
+			if test.hasErr {
+				if err == nil {
+					t.Fatalf("Search() = %+v, want an error", result)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Search() returned an error: %s", err.Error())
+			}
+
+			movie, ok := result.(*torrentRenamer.Movie)
+			if !ok {
+				t.Fatalf("Search() = %T, want a movie", result)
+			}
+
+			if movie.Name != test.want.Name || movie.Year != test.want.Year || movie.Ext != test.want.Ext {
+				t.Errorf("Search() = %s (%d).%s, want %s (%d).%s", movie.Name, movie.Year, movie.Ext, test.want.Name, test.want.Year, test.want.Ext)
+			}
+
+			if movie.ImdbID != "tt0133093" || movie.Runtime != 136 || len(movie.Genres) != 2 {
+				t.Errorf("Search() metadata = %+v, want the metadata of tt0133093", movie.Metadata)
+			}
+		})
+	}
+}
+
+func TestOMDBSearchShow(t *testing.T) {
+	replayOMDB(t)
+
+	var video torrentRenamer.Video = &torrentRenamer.Show{Name: "The Office", Season: 1, Episode: 2, Ext: "mkv"}
+
+	result, err := OMDBService{}.Search(&video)
+	if err != nil {
+		t.Fatalf("Search() returned an error: %s", err.Error())
+	}
+
+	show, ok := result.(*torrentRenamer.Show)
+	if !ok {
+		t.Fatalf("Search() = %T, want a show", result)
+	}
+
+	if show.Name != "The Office" || show.Title != "Diversity Day" || show.Season != 1 || show.Episode != 2 || show.Ext != "mkv" {
+		t.Errorf("Search() = %+v, want The Office S01E02 Diversity Day", show)
+	}
+
+	if show.ImdbID != "tt0664514" {
+		t.Errorf("Search() ImdbID = %s, want tt0664514", show.ImdbID)
+	}
+}
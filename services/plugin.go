@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"torrentRenamer"
+	"torrentRenamer/config"
+	"torrentRenamer/exec"
+	"torrentRenamer/logger"
+	"torrentRenamer/match"
+	"torrentRenamer/util"
+)
+
+// PluginService - A service backed by an external program. The program gets a
+// pluginRequest as JSON on stdin and answers with a pluginResponse as JSON on
+// stdout.
+type PluginService struct {
+	name          string
+	command       string
+	args          []string
+	capabilities  Capability
+	timeout       time.Duration
+	movieTemplate string
+	showTemplate  string
+}
+
+type pluginRequest struct {
+	Type     string               `json:"type"`
+	ID       string               `json:"id,omitempty"`
+	Video    torrentRenamer.Video `json:"video"`
+	Language string               `json:"language,omitempty"`
+	Region   string               `json:"region,omitempty"`
+}
+
+type pluginResponse struct {
+	Result     json.RawMessage   `json:"result"`
+	Candidates []json.RawMessage `json:"candidates"`
+	Error      string            `json:"error"`
+}
+
+const defaultPluginTimeout = 30 * time.Second
+
+// RegisterPlugins - Registers the plugins in the config as services. Plugins
+// with an invalid config are skipped.
+func RegisterPlugins() {
+	config := config.GetConfig()
+
+	for _, plugin := range config.Plugins {
+		movieTemplate, showTemplate := plugin.RenameTemplates.Movies, plugin.RenameTemplates.Shows
+		if movieTemplate == "" {
+			movieTemplate = config.RenameTemplates.Movies
+		}
+
+		if showTemplate == "" {
+			showTemplate = config.RenameTemplates.Shows
+		}
+
+		capabilities := CapMovies | CapShows
+		if len(plugin.Capabilities) > 0 {
+			var err error
+
+			capabilities, err = ParseCapabilities(plugin.Capabilities)
+			if err != nil {
+				logger.Errorf("Skipping plugin %s, its config is invalid: %s", plugin.Name, err.Error())
+				continue
+			}
+		}
+
+		timeout := time.Duration(plugin.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = defaultPluginTimeout
+		}
+
+		RegisterService(PluginService{
+			name:          plugin.Name,
+			command:       plugin.Command,
+			args:          plugin.Args,
+			capabilities:  capabilities,
+			timeout:       timeout,
+			movieTemplate: movieTemplate,
+			showTemplate:  showTemplate,
+		})
+	}
+}
+
+func (p *PluginService) run(request *pluginRequest) (pluginResponse, error) {
+	var ret pluginResponse
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return ret, err
+	}
+
+	args := append([]string{p.command}, p.args...)
+
+	// A hung plugin would otherwise hold up the whole batch
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	err = exec.ExecuteContext(ctx, args, bytes.NewReader(input), &stdout, &stderr)
+	if ctx.Err() == context.DeadlineExceeded {
+		return ret, fmt.Errorf("Plugin %s did not answer within %s", p.name, p.timeout.String())
+	}
+
+	if err != nil {
+		return ret, fmt.Errorf("Plugin %s failed: %s %s", p.name, err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	if err = json.Unmarshal(stdout.Bytes(), &ret); err != nil {
+		return ret, fmt.Errorf("Plugin %s returned invalid JSON: %s", p.name, err.Error())
+	}
+
+	if ret.Error != "" {
+		return ret, fmt.Errorf("Plugin %s: %s", p.name, ret.Error)
+	}
+
+	return ret, nil
+}
+
+func (p *PluginService) decodeVideo(raw json.RawMessage, original torrentRenamer.Video) (torrentRenamer.Video, error) {
+	var ret torrentRenamer.Video
+
+	if original.IsMovie() {
+		ret = &torrentRenamer.Movie{}
+	} else {
+		ret = &torrentRenamer.Show{}
+	}
+
+	if err := json.Unmarshal(raw, ret); err != nil {
+		return ret, err
+	}
+
+	ret.SetExt(original.GetExt())
+
+	return ret, nil
+}
+
+// pluginCandidate - Returns what the video is scored by against the parsed one
+func pluginCandidate(video torrentRenamer.Video) match.Candidate {
+	if movie, ok := video.(*torrentRenamer.Movie); ok {
+		return match.Candidate{Title: movie.Name, Year: movie.Year}
+	}
+
+	return match.Candidate{Title: video.GetName()}
+}
+
+// chooseCandidate - Returns the candidate the user chooses, or the one most
+// similar to the video if there is only one or no prompts. Candidates found by
+// an ID are trusted without being scored.
+func (p *PluginService) chooseCandidate(video torrentRenamer.Video, candidates []torrentRenamer.Video, byID bool) (torrentRenamer.Video, error) {
+	if len(candidates) == 1 || config.GetConfig().RenameWithoutPrompt {
+		if byID {
+			return candidates[0], nil
+		}
+
+		scored := make([]match.Candidate, len(candidates))
+		for i, candidate := range candidates {
+			scored[i] = pluginCandidate(candidate)
+		}
+
+		parsed := pluginCandidate(video)
+
+		best, err := bestMatch(parsed.Title, parsed.Year, scored)
+		if err != nil {
+			return nil, err
+		}
+
+		return candidates[best], nil
+	}
+
+	options := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		options[i] = candidate.GetNewName()
+	}
+
+	choice := util.GetOption(fmt.Sprintf("%s found multiple matches, which one is correct?", p.name), options)
+	if choice < 0 || choice >= len(candidates) {
+		return nil, nil
+	}
+
+	return candidates[choice], nil
+}
+
+func (p PluginService) Name() string {
+	return p.name
+}
+
+func (p PluginService) Capabilities() Capability {
+	return p.capabilities
+}
+
+func (p PluginService) Search(v *torrentRenamer.Video) (torrentRenamer.Video, error) {
+	return p.lookup("", *v)
+}
+
+func (p PluginService) LookupByID(id string, v *torrentRenamer.Video) (torrentRenamer.Video, error) {
+	return p.lookup(id, *v)
+}
+
+func (p *PluginService) lookup(id string, video torrentRenamer.Video) (torrentRenamer.Video, error) {
+	conf := config.GetConfig()
+
+	if id == "" {
+		id, _ = config.GetIdOverride(video.GetName())
+	}
+
+	request := pluginRequest{
+		Type:     "show",
+		ID:       id,
+		Video:    video,
+		Language: conf.Language,
+		Region:   conf.Region,
+	}
+	if video.IsMovie() {
+		request.Type = "movie"
+	}
+
+	res, err := p.run(&request)
+	if err != nil {
+		return &torrentRenamer.Show{}, err
+	}
+
+	raws := res.Candidates
+	if len(res.Result) > 0 && string(res.Result) != "null" {
+		raws = []json.RawMessage{res.Result}
+	}
+
+	candidates := make([]torrentRenamer.Video, 0, len(raws))
+
+	for _, raw := range raws {
+		candidate, err := p.decodeVideo(raw, video)
+		if err != nil {
+			return &torrentRenamer.Show{}, err
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	var result torrentRenamer.Video
+
+	if len(candidates) > 0 {
+		result, err = p.chooseCandidate(video, candidates, id != "")
+		if err != nil {
+			return &torrentRenamer.Show{}, err
+		}
+	}
+
+	if result == nil {
+		return &torrentRenamer.Show{}, errors.New("Could not find with plugin " + p.name)
+	}
+
+	return result, nil
+}
+
+func (p PluginService) IsAvailable() bool {
+	return p.command != "" && exec.IsCommandInPath(p.command)
+}
+
+func (p PluginService) GetNewName(video *torrentRenamer.Video) (string, error) {
+	result, err := p.Search(video)
+	if err != nil {
+		return "", err
+	}
+
+	movieTemplate, showTemplate := p.GetRenameTemplates()
+
+	return getNewNameFromResult(result, movieTemplate, showTemplate)
+}
+
+func (p PluginService) GetRenameTemplates() (string, string) {
+	return p.movieTemplate, p.showTemplate
+}
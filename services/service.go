@@ -1,8 +1,12 @@
 package services
 
 import (
+	"fmt"
 	"torrentRenamer"
 	"torrentRenamer/config"
+	"torrentRenamer/logger"
+	"torrentRenamer/match"
+	"torrentRenamer/util"
 )
 
 var (
@@ -12,10 +16,54 @@ var (
 
 type Service interface {
 	Name() string
+	Capabilities() Capability
 	Search(*torrentRenamer.Video) (torrentRenamer.Video, error)
+	// LookupByID looks the video up by the movie's or show's ID in the service
+	LookupByID(string, *torrentRenamer.Video) (torrentRenamer.Video, error)
 	IsAvailable() bool
 	GetNewName(*torrentRenamer.Video) (string, error)
-	GetServiceName() string
+	// GetRenameTemplates - Returns the movie and show templates for results
+	GetRenameTemplates() (string, string)
+}
+
+// BatchService - Implemented by services that can look up the videos of a whole
+// batch more efficiently than one at a time.
+type BatchService interface {
+	PrepareBatch([]torrentRenamer.Video)
+}
+
+type EpisodeNotFoundError struct {
+	Name    string
+	Season  int
+	Episode int
+}
+
+func (e *EpisodeNotFoundError) Error() string {
+	return fmt.Sprintf("%s has no episode %d in season %d", e.Name, e.Episode, e.Season)
+}
+
+// NoMatchError - Returned when a service found results, but none of them were
+// similar enough to the parsed video to be trusted
+type NoMatchError struct {
+	Title     string
+	BestTitle string
+	Score     float64
+}
+
+func (e *NoMatchError) Error() string {
+	if e.BestTitle == "" {
+		return fmt.Sprintf("No result is similar enough to %s", e.Title)
+	}
+
+	return fmt.Sprintf("Best result for %s was %s, which only scored %.2f", e.Title, e.BestTitle, e.Score)
+}
+
+type NoServiceError struct {
+	Required Capability
+}
+
+func (e *NoServiceError) Error() string {
+	return fmt.Sprintf("No available service supports %s", e.Required.String())
 }
 
 func RegisterService(service Service) {
@@ -29,7 +77,7 @@ func GetRegistedServices() []Service {
 func IsDefault(service Service) bool {
 	config := config.GetConfig()
 
-	return config.DefaultService == service.GetServiceName()
+	return config.DefaultService == service.Name()
 }
 
 func GetDefaultService() *Service {
@@ -46,17 +94,134 @@ func GetDefaultService() *Service {
 	return defaultService
 }
 
-func GetDefaultServiceResults(video *torrentRenamer.Video) (string, error) {
-	service := (*GetDefaultService())
+// GetServiceForVideo - Returns the default service if it can handle the video,
+// otherwise the first registered service that can. Of those, services that also
+// have the preferred capabilities are chosen over those that do not.
+func GetServiceForVideo(video torrentRenamer.Video) (Service, error) {
+	required := RequiredCapabilities(video)
 
-	var result string
-	var err error
+	if service := GetDefaultService(); service != nil && supports(*service, required) {
+		return *service, nil
+	}
 
-	if service != nil {
-		if service.IsAvailable() {
-			result, err = service.GetNewName(video)
+	for _, capabilities := range []Capability{required | PreferredCapabilities(), required} {
+		for _, service := range GetRegistedServices() {
+			if supports(service, capabilities) {
+				return service, nil
+			}
 		}
 	}
 
-	return result, err
+	return nil, &NoServiceError{Required: required}
+}
+
+// GetDefaultServiceResults - Looks the video up with the service chosen for it,
+// by its ID if it has an ID override, returning the service, the found video
+// and its new name.
+func GetDefaultServiceResults(video *torrentRenamer.Video) (Service, torrentRenamer.Video, string, error) {
+	service, err := GetServiceForVideo(*video)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var result torrentRenamer.Video
+
+	if id, pinned := config.GetIdOverride((*video).GetName()); pinned {
+		logger.Debugf("Looking up %s by its ID %s with %s", (*video).GetName(), id, service.Name())
+		result, err = service.LookupByID(id, video)
+	} else {
+		logger.Debugf("Looking up %s with %s", (*video).GetName(), service.Name())
+		result, err = service.Search(video)
+	}
+
+	if err != nil {
+		return service, nil, "", err
+	}
+
+	// Services know the video, not the file, so the parsed release is kept
+	*result.GetRelease() = *(*video).GetRelease()
+
+	movieTemplate, showTemplate := service.GetRenameTemplates()
+
+	name, err := getNewNameFromResult(result, movieTemplate, showTemplate)
+	if err != nil {
+		return service, nil, "", err
+	}
+
+	return service, result, name, nil
+}
+
+// PrepareServiceBatches - Lets every service prefetch whatever it needs for the
+// videos routed to it before they are looked up individually.
+func PrepareServiceBatches(videos []torrentRenamer.Video) {
+	batches := make(map[string][]torrentRenamer.Video)
+	batchServices := make(map[string]BatchService)
+
+	for _, video := range videos {
+		service, err := GetServiceForVideo(video)
+		if err != nil {
+			continue
+		}
+
+		if batchService, ok := service.(BatchService); ok {
+			batches[service.Name()] = append(batches[service.Name()], video)
+			batchServices[service.Name()] = batchService
+		}
+	}
+
+	for name, batch := range batches {
+		batchServices[name].PrepareBatch(batch)
+	}
+}
+
+func getNewNameFromResult(result torrentRenamer.Video, movieTemplate string, showTemplate string) (string, error) {
+	if !result.IsValid() {
+		return "", fmt.Errorf("could not find valid video results")
+	}
+
+	if result.IsMovie() {
+		return util.InsertTemplateData(movieTemplate, result)
+	}
+
+	return util.InsertTemplateData(showTemplate, result)
+}
+
+func getMatchThreshold() float64 {
+	threshold := config.GetConfig().Matching.Threshold
+	if threshold < 0 {
+		return match.DefaultThreshold
+	}
+
+	return threshold
+}
+
+func getYearTolerance() int {
+	tolerance := config.GetConfig().Matching.YearTolerance
+	if tolerance < 0 {
+		return match.DefaultYearTolerance
+	}
+
+	return tolerance
+}
+
+// bestMatch - Returns the index of the candidate most similar to the title and
+// year, or a NoMatchError if none is similar enough
+func bestMatch(title string, year int, candidates []match.Candidate) (int, error) {
+	tolerance := getYearTolerance()
+
+	best, score := match.Best(title, year, candidates, getMatchThreshold(), tolerance)
+	if best < 0 {
+		var bestTitle string
+
+		for _, candidate := range candidates {
+			if match.Score(title, year, candidate, tolerance) == score {
+				bestTitle = candidate.Title
+				break
+			}
+		}
+
+		return best, &NoMatchError{Title: title, BestTitle: bestTitle, Score: score}
+	}
+
+	return best, nil
 }
@@ -0,0 +1,218 @@
+package tags
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"torrentRenamer"
+	"torrentRenamer/exec"
+)
+
+const (
+	mkvTagger = "mkvpropedit"
+	mp4Tagger = "ffmpeg"
+)
+
+// Matroska target type values, see https://www.matroska.org/technical/tagging.html
+const (
+	targetCollection = 70
+	targetSeason     = 60
+	targetMovie      = 50
+)
+
+type simpleTag struct {
+	Name   string `xml:"Name"`
+	String string `xml:"String"`
+}
+
+type targets struct {
+	TargetTypeValue int `xml:"TargetTypeValue"`
+}
+
+type tag struct {
+	Targets    targets     `xml:"Targets"`
+	SimpleTags []simpleTag `xml:"Simple"`
+}
+
+type mkvTags struct {
+	XMLName xml.Name `xml:"Tags"`
+	Tags    []tag    `xml:"Tag"`
+}
+
+// fields - The values written into the container, empty ones are left out
+type fields struct {
+	Title       string
+	Year        int
+	Show        string
+	Season      int
+	Episode     int
+	Description string
+	Genres      []string
+	ImdbID      string
+}
+
+func getFields(video torrentRenamer.Video) fields {
+	var ret fields
+
+	switch v := video.(type) {
+	case *torrentRenamer.Movie:
+		ret = fields{
+			Title:       v.Name,
+			Year:        v.Year,
+			Description: v.Plot,
+			Genres:      v.Genres,
+			ImdbID:      v.ImdbID,
+		}
+	case *torrentRenamer.Show:
+		ret = fields{
+			Title:       v.Title,
+			Show:        v.Name,
+			Season:      v.Season,
+			Episode:     v.Episode,
+			Description: v.Plot,
+			Genres:      v.Genres,
+			ImdbID:      v.ImdbID,
+		}
+
+		if ret.Title == "" {
+			ret.Title = fmt.Sprintf("%s - S%02dE%02d", v.Name, v.Season, v.Episode)
+		}
+	}
+
+	return ret
+}
+
+func appendTag(tags []simpleTag, name string, value string) []simpleTag {
+	if value == "" {
+		return tags
+	}
+
+	return append(tags, simpleTag{Name: name, String: value})
+}
+
+func appendNumberTag(tags []simpleTag, name string, value int) []simpleTag {
+	if value == 0 {
+		return tags
+	}
+
+	return appendTag(tags, name, strconv.Itoa(value))
+}
+
+// getMKVTags - Shows are tagged at the collection (show), season and episode
+// levels, movies only at the movie level
+func getMKVTags(f fields) mkvTags {
+	ret := mkvTags{}
+
+	if f.Show != "" {
+		ret.Tags = append(ret.Tags,
+			tag{Targets: targets{targetCollection}, SimpleTags: appendTag(nil, "TITLE", f.Show)},
+			tag{Targets: targets{targetSeason}, SimpleTags: appendNumberTag(nil, "PART_NUMBER", f.Season)},
+		)
+	}
+
+	simpleTags := appendTag(nil, "TITLE", f.Title)
+	simpleTags = appendNumberTag(simpleTags, "PART_NUMBER", f.Episode)
+	simpleTags = appendNumberTag(simpleTags, "DATE_RELEASED", f.Year)
+	simpleTags = appendTag(simpleTags, "DESCRIPTION", f.Description)
+	simpleTags = appendTag(simpleTags, "GENRE", strings.Join(f.Genres, ", "))
+	simpleTags = appendTag(simpleTags, "IMDB", f.ImdbID)
+
+	ret.Tags = append(ret.Tags, tag{Targets: targets{targetMovie}, SimpleTags: simpleTags})
+
+	return ret
+}
+
+func writeMKV(videoPath string, f fields) error {
+	if !exec.IsCommandInPath(mkvTagger) {
+		return fmt.Errorf("Command \"%s\" is not in path, cannot tag %s", mkvTagger, videoPath)
+	}
+
+	data, err := xml.MarshalIndent(getMKVTags(f), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tagsFile, err := ioutil.TempFile("", "torrentRenamer-tags-*.xml")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tagsFile.Name())
+
+	_, err = tagsFile.Write(append([]byte(xml.Header), data...))
+	if closeErr := tagsFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.ExecuteCommand(mkvTagger, videoPath,
+		"--edit", "info", "--set", "title="+f.Title,
+		"--tags", "global:"+tagsFile.Name(),
+	)
+
+	return err
+}
+
+// writeMP4 - ffmpeg cannot edit files in place, so the streams are copied into
+// a temporary file that then replaces the video
+func writeMP4(videoPath string, f fields) error {
+	if !exec.IsCommandInPath(mp4Tagger) {
+		return fmt.Errorf("Command \"%s\" is not in path, cannot tag %s", mp4Tagger, videoPath)
+	}
+
+	ext := filepath.Ext(videoPath)
+	tmpPath := strings.TrimSuffix(videoPath, ext) + ".tagging" + ext
+
+	args := []string{"-nostdin", "-y", "-i", videoPath, "-map", "0", "-c", "copy"}
+
+	metadata := appendTag(nil, "title", f.Title)
+	metadata = appendNumberTag(metadata, "date", f.Year)
+	metadata = appendTag(metadata, "description", f.Description)
+	metadata = appendTag(metadata, "synopsis", f.Description)
+	metadata = appendTag(metadata, "genre", strings.Join(f.Genres, ", "))
+
+	// iTunes media kinds, 10 is a TV show and 9 a movie
+	if f.Show != "" {
+		metadata = appendTag(metadata, "show", f.Show)
+		metadata = appendTag(metadata, "season_number", strconv.Itoa(f.Season))
+		metadata = appendTag(metadata, "episode_sort", strconv.Itoa(f.Episode))
+		metadata = appendTag(metadata, "episode_id", fmt.Sprintf("S%02dE%02d", f.Season, f.Episode))
+		metadata = appendTag(metadata, "media_type", "10")
+	} else {
+		metadata = appendTag(metadata, "media_type", "9")
+	}
+
+	for _, tag := range metadata {
+		args = append(args, "-metadata", tag.Name+"="+tag.String)
+	}
+
+	if _, err := exec.ExecuteCommand(mp4Tagger, append(args, tmpPath)...); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, videoPath)
+}
+
+// Write - Embeds the video's title, year, show, season, episode and description
+// into the container. Only MKV and MP4 (M4V, MOV) files can be tagged, other
+// formats are left as they are.
+func Write(videoPath string, video torrentRenamer.Video) error {
+	f := getFields(video)
+
+	switch strings.ToLower(filepath.Ext(videoPath)) {
+	case ".mkv":
+		return writeMKV(videoPath, f)
+	case ".mp4", ".m4v", ".mov":
+		return writeMP4(videoPath, f)
+	}
+
+	return nil
+}
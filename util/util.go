@@ -3,11 +3,14 @@ package util
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 )
 
@@ -59,6 +62,58 @@ func EscapeSpaces(str string) string {
 	return strings.Join(strings.Split(str, " "), "\\ ")
 }
 
+// SplitArgs - Splits a command line into its args at spaces that are neither
+// quoted nor escaped with a backslash, removing the quotes and backslashes
+func SplitArgs(str string) []string {
+	ret := make([]string, 0)
+	var arg strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+
+	for _, r := range str {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				ret = append(ret, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		ret = append(ret, arg.String())
+	}
+
+	return ret
+}
+
+// PreferTitle - Returns the first title that is not empty, e.g. a localized
+// title with the original one as fallback
+func PreferTitle(titles ...string) string {
+	for _, title := range titles {
+		if title != "" {
+			return title
+		}
+	}
+
+	return ""
+}
+
 func InsertTemplateData(templateString string, data interface{}) (string, error) {
 	var builder strings.Builder
 
@@ -77,6 +132,17 @@ func InsertTemplateData(templateString string, data interface{}) (string, error)
 			return JoinPaths(homeDir, path), nil
 		},
 		"escapeSpaces": EscapeSpaces,
+		"first": func(list []string) string {
+			if len(list) == 0 {
+				return ""
+			}
+
+			return list[0]
+		},
+		"join": func(list []string, sep string) string {
+			return strings.Join(list, sep)
+		},
+		"preferTitle": PreferTitle,
 	}).Parse(templateString)
 	if err != nil {
 		return "", err
@@ -90,9 +156,20 @@ func InsertTemplateData(templateString string, data interface{}) (string, error)
 	return builder.String(), nil
 }
 
+// PromptOutput - Where prompts are written, stdout unless it is reserved for
+// machine-readable output
+var PromptOutput io.Writer = os.Stdout
+
+// promptLock - Videos are processed concurrently, but only one of them may
+// prompt and read stdin at a time
+var promptLock sync.Mutex
+
 func GetYesOrNo(prompt string) bool {
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("%s [Y/N]: ", prompt)
+	fmt.Fprintf(PromptOutput, "%s [Y/N]: ", prompt)
 	text, _ := reader.ReadString('\n')
 
 	switch strings.TrimSpace(text) {
@@ -104,33 +181,61 @@ func GetYesOrNo(prompt string) bool {
 }
 
 func GetOption(prompt string, options []string) int {
-	fmt.Println(prompt)
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	fmt.Fprintln(PromptOutput, prompt)
 
 	for i, option := range options {
-		fmt.Printf("%d. %s", i+1, option)
+		fmt.Fprintf(PromptOutput, "%d. %s\n", i+1, option)
 	}
 
 	reader := bufio.NewReader(os.Stdin)
 	input, _ := reader.ReadString('\n')
 
-	choice, _ := strconv.Atoi(input)
+	choice, _ := strconv.Atoi(strings.TrimSpace(input))
 
 	return choice - 1
 }
 
-func MoveFile(src string, dest string, prompt bool) error {
-	var err error
-	move := true
+// MoveFile - Moves src to dest, creating missing directories. Returns whether
+// the file was moved, which it is not if the prompt is declined.
+func MoveFile(src string, dest string, prompt bool) (bool, error) {
+	if prompt && !GetYesOrNo(fmt.Sprintf("Move file\n'%s'\nto\n'%s'?\n", src, dest)) {
+		return false, nil
+	}
 
-	if prompt {
-		move = GetYesOrNo(fmt.Sprintf("Move file\n'%s'\nto\n'%s'?\n", src, dest))
+	err := os.MkdirAll(filepath.Dir(dest), 0755)
+	if err == nil {
+		err = os.Rename(src, dest)
 	}
 
-	if move {
-		err = os.MkdirAll(filepath.Dir(dest), 0644)
-		if err == nil {
-			err = os.Rename(src, dest)
-		}
+	return err == nil, err
+}
+
+// WriteFile - Writes the data to a temporary file of its own next to the path
+// and renames it over the path, so concurrent writers never mix their data
+func WriteFile(path string, data []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmpFile.Name())
 	}
 
 	return err